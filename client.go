@@ -1,117 +1,513 @@
 package prox
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
-	"fmt"
 	"io"
 	"net"
 	"os"
-	"text/tabwriter"
+	"strconv"
 	"time"
 
+	"github.com/fgrosse/prox/proxpb"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// A Client connects to a Server via a unix socket to provide access to a
-// running prox server.
+// A Client connects to a Server via a unix socket (or via TCP, see
+// NewRemoteClient) to provide access to a running prox server. It uses the
+// gRPC service of the Server (see proxpb/prox.proto) and returns typed values
+// so the output can be rendered by the caller.
+//
+// A Client is safe for concurrent use since gRPC already multiplexes all
+// commands and cancellable streams over its single connection.
 type Client struct {
-	conn   net.Conn
+	conn   *grpc.ClientConn
+	rpc    proxpb.ProxClient
 	logger *zap.Logger
-	buf    *bufio.Reader
 }
 
+// connectTimeout is the maximum time NewClient waits for a connection.
+const connectTimeout = 5 * time.Second
+
+// The delays between the attempts to reconnect to a server that was restarted
+// while following its output (see TailOptions.FollowRestarts).
+const (
+	reconnectDelay    = 100 * time.Millisecond
+	maxReconnectDelay = 5 * time.Second
+)
+
 // NewClient creates a new prox Client and immediately connects it to a prox
 // Server via a unix socket. It is the callers responsibility to eventually
 // close the client to release the underlying socket connection.
 func NewClient(socketPath string, debug bool) (*Client, error) {
-	c, err := net.Dial("unix", socketPath)
+	return dialClient(socketPath, NewLogger(os.Stderr, debug),
+		grpc.WithInsecure(), // unix sockets are only accessible by the current user
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}),
+	)
+}
+
+// dialClient connects a new Client using the given dial options.
+func dialClient(addr string, logger *zap.Logger, opts ...grpc.DialOption) (*Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	opts = append(opts,
+		grpc.WithBlock(),
+		grpc.FailOnNonTempDialError(true),
+		grpc.WithUnaryInterceptor(versionUnaryInterceptor),
+		grpc.WithStreamInterceptor(versionStreamInterceptor),
+	)
+	conn, err := grpc.DialContext(ctx, addr, opts...)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to connect to prox socket")
+		return nil, errors.Wrapf(err, "failed to connect to prox at %s", addr)
 	}
 
 	return &Client{
-		conn:   c,
-		logger: NewLogger(os.Stderr, debug),
-		buf:    bufio.NewReader(c),
+		conn:   conn,
+		rpc:    proxpb.NewProxClient(conn),
+		logger: logger,
 	}, nil
 }
 
-// List fetches a list of running processes from the server and prints it via
-// the given output.
-func (c *Client) List(ctx context.Context, output io.Writer) error {
-	err := c.sendMessage(socketMessage{Command: "LIST"})
+// versionUnaryInterceptor sends the protocol version of the Client with every
+// request and checks the protocol version of the server.
+func versionUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var header metadata.MD
+	ctx = metadata.AppendToOutgoingContext(ctx, versionMetadataKey, strconv.Itoa(ProtocolVersion))
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+	if verr := checkServerVersion(header, err); verr != nil {
+		return verr
+	}
+
+	return err
+}
+
+// versionStreamInterceptor is the equivalent of the versionUnaryInterceptor
+// for streams. The version of the server is checked when the header of the
+// stream is received.
+func versionStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, versionMetadataKey, strconv.Itoa(ProtocolVersion))
+	stream, err := streamer(ctx, desc, cc, method, opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var resp []ProcessInfo
-	err = json.NewDecoder(c.conn).Decode(&resp)
+	return versionedStream{stream}, nil
+}
+
+// A versionedStream checks the protocol version of the server in the header
+// of a stream.
+type versionedStream struct {
+	grpc.ClientStream
+}
+
+func (s versionedStream) Header() (metadata.MD, error) {
+	header, err := s.ClientStream.Header()
+	if verr := checkServerVersion(header, err); verr != nil {
+		return nil, verr
+	}
+
+	return header, err
+}
+
+func (s versionedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		return nil
+	}
+
+	// the stream has ended so the header is available immediately
+	header, _ := s.ClientStream.Header()
+	if verr := checkServerVersion(header, err); verr != nil {
+		return verr
+	}
+
+	return err
+}
+
+// checkServerVersion checks the protocol version in the header of a response.
+// Servers of the first prox.v1 release did not send their version so if a
+// server without a version does not implement a command, it is reported as
+// unsupported version as well.
+func checkServerVersion(header metadata.MD, err error) error {
+	if len(header.Get(versionMetadataKey)) == 0 {
+		if status.Code(err) == codes.Unimplemented {
+			return unsupportedVersionError("of the server")
+		}
+		return nil
+	}
+
+	return checkVersion(header)
+}
+
+// List fetches the running and scheduled processes from the server.
+func (c *Client) List(ctx context.Context) ([]ProcessInfo, error) {
+	list, err := c.rpc.List(ctx, new(proxpb.ListRequest))
 	if err != nil {
-		return errors.Wrap(err, "failed to decode server response")
+		return nil, fromGRPCError(err)
 	}
 
-	w := tabwriter.NewWriter(output, 8, 8, 2, ' ', 0)
-	fmt.Fprintln(w, "NAME\tPID\tUPTIME")
+	resp := make([]ProcessInfo, len(list.Processes))
+	for i, p := range list.Processes {
+		resp[i] = processFromProto(p)
+	}
+
+	return resp, nil
+}
 
-	for _, inf := range resp {
-		fmt.Fprintln(w, fmt.Sprintf(
-			"%s\t%v\t%v",
-			inf.Name, inf.PID, inf.Uptime.Round(time.Second)),
-		)
+// Reload requests the server to reload the configuration of all processes and
+// returns the applied changes.
+func (c *Client) Reload(ctx context.Context) (ReloadDiff, error) {
+	resp, err := c.rpc.Reload(ctx, new(proxpb.ReloadRequest))
+	if err != nil {
+		return ReloadDiff{}, fromGRPCError(err)
 	}
 
-	return w.Flush()
+	diff := ReloadDiff{Added: resp.Added, Removed: resp.Removed}
+	for _, c := range resp.Changed {
+		diff.Changed = append(diff.Changed, ProcessChange{Name: c.Name, Fields: c.Fields})
+	}
+
+	return diff, nil
+}
+
+// Signal requests the server to send a signal (e.g. "SIGUSR1") to all
+// processes that are selected by the patterns and returns their names. Each
+// pattern is either the name of a process, a glob pattern like "api-*" (see
+// AllProcesses) or an exclusion like "!worker". If group is true, the signal
+// is sent to the whole process group of each process.
+func (c *Client) Signal(ctx context.Context, patterns []string, signal string, group bool) ([]string, error) {
+	resp, err := c.rpc.Signal(ctx, &proxpb.SignalRequest{
+		Processes: patterns,
+		Signal:    signal,
+		Group:     group,
+	})
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+
+	return resp.Processes, nil
+}
+
+// StartProcesses requests the server to start all processes that are selected
+// by the patterns (see Signal) and returns their names.
+func (c *Client) StartProcesses(ctx context.Context, patterns ...string) ([]string, error) {
+	return c.processResponse(c.rpc.Start(ctx, &proxpb.ProcessRequest{Processes: patterns}))
+}
+
+// StopProcesses requests the server to stop all processes that are selected
+// by the patterns (see Signal) and returns their names.
+func (c *Client) StopProcesses(ctx context.Context, patterns ...string) ([]string, error) {
+	return c.processResponse(c.rpc.Stop(ctx, &proxpb.ProcessRequest{Processes: patterns}))
+}
+
+// RestartProcesses requests the server to restart all processes that are
+// selected by the patterns (see Signal) and returns their names.
+func (c *Client) RestartProcesses(ctx context.Context, patterns ...string) ([]string, error) {
+	return c.processResponse(c.rpc.Restart(ctx, &proxpb.ProcessRequest{Processes: patterns}))
+}
+
+// PauseProcesses requests the server to pause all processes that are selected
+// by the patterns (see Signal) and returns their names.
+func (c *Client) PauseProcesses(ctx context.Context, patterns ...string) ([]string, error) {
+	return c.processResponse(c.rpc.Pause(ctx, &proxpb.ProcessRequest{Processes: patterns}))
+}
+
+// ResumeProcesses requests the server to resume all paused processes that are
+// selected by the patterns (see Signal) and returns their names.
+func (c *Client) ResumeProcesses(ctx context.Context, patterns ...string) ([]string, error) {
+	return c.processResponse(c.rpc.Resume(ctx, &proxpb.ProcessRequest{Processes: patterns}))
+}
+
+// Mark requests the server to highlight the output of all processes that are
+// selected by the patterns (see Signal) in its terminal and returns their
+// names.
+func (c *Client) Mark(ctx context.Context, m Mark, patterns ...string) ([]string, error) {
+	return c.processResponse(c.rpc.Mark(ctx, &proxpb.MarkRequest{
+		Processes: patterns,
+		Style:     string(m.Style),
+		Where:     m.Where,
+	}))
+}
+
+// Unmark requests the server to remove the marks of all processes that are
+// selected by the patterns (see Signal) and returns their names.
+func (c *Client) Unmark(ctx context.Context, patterns ...string) ([]string, error) {
+	return c.processResponse(c.rpc.Unmark(ctx, &proxpb.ProcessRequest{Processes: patterns}))
+}
+
+func (c *Client) processResponse(resp *proxpb.ProcessResponse, err error) ([]string, error) {
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+
+	return resp.Processes, nil
+}
+
+// Shutdown requests the server to stop all processes gracefully and blocks
+// until they have finished or the context is done.
+func (c *Client) Shutdown(ctx context.Context) error {
+	_, err := c.rpc.Shutdown(ctx, new(proxpb.ShutdownRequest))
+	if status.Code(err) == codes.Unavailable {
+		// the server has stopped before it could respond
+		return nil
+	}
+
+	return fromGRPCError(err)
+}
+
+// TailOptions control which output is returned by Client.Tail(…).
+type TailOptions struct {
+	History  int  // number of recent lines to send first (all lines of the history if negative)
+	NoFollow bool // close the channel after the recent lines instead of following the output
+
+	FollowRestarts bool // reconnect and resume following the output when the server was restarted
+
+	// The following filters are evaluated by the server. Empty values are ignored.
+	Grep  string   // a regular expression that must match the raw line
+	Level string   // the minimum level of a line (e.g. "warn")
+	Where []string // conditions in the form "key=value" or "key=/regex/flags" that the fields of a line must match
 }
 
-// Tail requests and "follows" the logs for a set of processes from a server and
-// prints them to the output. This function blocks until the context is done or
-// the connection to the server is closed by either side.
-func (c *Client) Tail(ctx context.Context, processNames []string, output io.Writer) error {
-	ctx, cancel := context.WithCancel(ctx)
+// A LogLine is received via Client.Tail(…). It either contains a line of
+// output, marks a gap in the output or contains the error that has ended the
+// stream.
+type LogLine struct {
+	OutputLine
+
+	// Gap is set instead of a line of output after the Client has reconnected
+	// to a restarted server (see TailOptions.FollowRestarts).
+	Gap *TailGap
 
-	err := c.sendMessage(socketMessage{Command: "TAIL", Args: processNames})
+	// Err is set on the last LogLine if the stream has ended because of an
+	// error. The channel is simply closed if the context is done or the server
+	// has closed the connection.
+	Err error
+}
+
+// A TailGap is the time in which the Client was disconnected from the server.
+type TailGap struct {
+	Disconnected time.Time
+	Reconnected  time.Time
+}
+
+// Tail requests and "follows" the logs of all processes that are selected by
+// the patterns (see Signal) from a server. Processes that are added later and
+// match the patterns are followed as well. The server sends every line along
+// with its decoded structured log message so it can be formatted regardless of
+// the output settings of the server (see OutputLine.Format). The most recent
+// lines of the processes are sent first if requested via the TailOptions.
+//
+// The returned channel is closed when the context is done or the connection
+// to the server is closed by either side. Errors of the request itself (e.g.
+// unknown processes) are returned immediately.
+//
+// If TailOptions.FollowRestarts is set, Tail waits until the server is
+// reachable again after it has closed the connection (e.g. because the stack
+// was restarted) and resumes following the output of the same processes.
+func (c *Client) Tail(ctx context.Context, patterns []string, opts TailOptions) (<-chan LogLine, error) {
+	req := &proxpb.TailRequest{
+		Processes: patterns,
+		History:   int32(opts.History),
+		NoFollow:  opts.NoFollow,
+		Grep:      opts.Grep,
+		Level:     opts.Level,
+		Where:     opts.Where,
+	}
+
+	stream, instance, err := c.tail(ctx, req)
 	if err != nil {
-		cancel()
-		return err
+		return nil, fromGRPCError(err)
 	}
 
-	lines := make(chan string)
+	lines := make(chan LogLine)
 	go func() {
+		defer close(lines)
+
+		send := func(l LogLine) bool {
+			select {
+			case lines <- l:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var last uint64 // sequence number of the last line that was sent
 		for {
-			line, err := c.buf.ReadString('\n')
+			err := c.receive(stream, &last, send)
+			if !opts.FollowRestarts || opts.NoFollow || !serverClosed(err) {
+				if err = c.streamError(err); err != nil {
+					send(LogLine{Err: err})
+				}
+				return
+			}
+
+			disconnected := time.Now()
+			c.logger.Info("Server closed connection, waiting for prox to be restarted")
+
+			// the recent output of the server contains everything that was
+			// emitted while we were disconnected. If it is still the same
+			// server, it only sends the lines we have not received yet.
+			req.History = -1
+			req.Instance, req.SinceSeq = instance, last
+			stream, instance, err = c.reconnectTail(ctx, req)
 			if err != nil {
-				if err == io.EOF {
-					c.logger.Info("Server closed connection")
-				} else {
-					c.logger.Error(err.Error())
+				if err = c.streamError(err); err != nil {
+					send(LogLine{Err: err})
 				}
+				return
+			}
 
-				// Cancel the context to return from the loop below.
-				cancel()
+			c.logger.Info("Reconnected to server", zap.Duration("gap", time.Since(disconnected)))
+			if !send(LogLine{Gap: &TailGap{Disconnected: disconnected, Reconnected: time.Now()}}) {
 				return
 			}
-			lines <- line
 		}
 	}()
 
+	return lines, nil
+}
+
+// tail starts a Tail stream and waits until the server follows the output. It
+// returns the instance of the output history of the server which is needed to
+// resume the stream later.
+func (c *Client) tail(ctx context.Context, req *proxpb.TailRequest) (stream proxpb.Prox_TailClient, instance string, err error) {
+	stream, err = c.rpc.Tail(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	header, err := stream.Header()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if v := header.Get(instanceMetadataKey); len(v) > 0 {
+		instance = v[0]
+	}
+
+	return stream, instance, nil
+}
+
+// reconnectTail starts a Tail stream again once the server is reachable. It
+// retries with an exponential backoff until the context is done.
+func (c *Client) reconnectTail(ctx context.Context, req *proxpb.TailRequest) (proxpb.Prox_TailClient, string, error) {
+	delay := reconnectDelay
 	for {
 		select {
 		case <-ctx.Done():
-			return nil
-		case l := <-lines:
-			_, err = fmt.Fprint(output, l)
+			return nil, "", status.FromContextError(ctx.Err()).Err()
+		case <-time.After(delay):
+		}
+
+		// do not wait for the backoff of the connection itself
+		c.conn.ResetConnectBackoff()
+
+		stream, instance, err := c.tail(ctx, req)
+		switch {
+		case err == nil:
+			return stream, instance, nil
+		case serverClosed(err), status.Code(err) == codes.NotFound:
+			// the processes may not have been started yet
+			c.logger.Debug("Failed to reconnect", zap.Error(err), zap.Duration("retry_in", delay))
+		default:
+			return nil, "", err
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// receive sends the lines of a Tail stream until it has ended and returns the
+// error that has ended it. The sequence number of the last line that was sent
+// is stored in last so the stream can be resumed after a reconnect.
+func (c *Client) receive(stream proxpb.Prox_TailClient, last *uint64, send func(LogLine) bool) error {
+	for {
+		pl, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		l := outputLineFromProto(pl)
+		if !send(LogLine{OutputLine: l}) {
+			return status.Error(codes.Canceled, "context canceled")
+		}
+
+		*last = l.Seq
+	}
+}
+
+// serverClosed returns true if the error was caused because the server has
+// closed the connection.
+func serverClosed(err error) bool {
+	return err == io.EOF || status.Code(err) == codes.Unavailable
+}
+
+// Events requests the lifecycle events of the Executor from the server and
+// returns all events that match the filter via the channel. The channel is
+// closed when the context is done or the connection to the server is closed by
+// either side.
+func (c *Client) Events(ctx context.Context, filter EventFilter) (<-chan Event, error) {
+	req := &proxpb.EventsRequest{Processes: filter.Processes}
+	for _, t := range filter.Types {
+		req.Types = append(req.Types, string(t))
+	}
+
+	stream, err := c.rpc.Events(ctx, req)
+	if err == nil {
+		_, err = stream.Header()
+	}
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for {
+			e, err := stream.Recv()
 			if err != nil {
-				return err
+				if err = c.streamError(err); err != nil {
+					c.logger.Error("Failed to receive events", zap.Error(err))
+				}
+				return
+			}
+
+			select {
+			case events <- eventFromProto(e):
+			case <-ctx.Done():
+				return
 			}
 		}
-	}
+	}()
+
+	return events, nil
 }
 
-func (c *Client) sendMessage(msg socketMessage) error {
-	return json.NewEncoder(c.conn).Encode(msg)
+// streamError converts the error that ended a stream. If the stream has ended
+// because the context is done or the server has closed the stream, nil is
+// returned.
+func (c *Client) streamError(err error) error {
+	switch {
+	case status.Code(err) == codes.Canceled:
+		return nil
+	case serverClosed(err):
+		c.logger.Info("Server closed connection")
+		return nil
+	default:
+		return fromGRPCError(err)
+	}
 }
 
 // Close closes the socket connection to the prox server.
@@ -120,6 +516,5 @@ func (c *Client) Close() error {
 		return nil
 	}
 
-	var _ = c.sendMessage(socketMessage{Command: "EXIT"})
 	return c.conn.Close()
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(attachOutputCmd)
+
+	flags := attachOutputCmd.Flags()
+	flags.String("log-file", DefaultLogFile, "path of the log file of the detached prox instance")
+	flags.String("pid-file", DefaultPIDFile, "path of the pidfile of the detached prox instance")
+	flags.BoolP("all", "a", false, "print the whole output since prox was started instead of only new output")
+}
+
+var attachOutputCmd = &cobra.Command{
+	Use:   "attach-output",
+	Short: "Follow the output of a prox instance that was started via --detach",
+	Long: `Follow the merged output of all processes of a prox instance that was started via --detach.
+
+Interrupting this command (e.g. via Ctrl-C) does not stop any processes. Use "prox stop" to stop them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		f, err := os.Open(viper.GetString("log-file"))
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer f.Close()
+
+		if !viper.GetBool("all") {
+			_, err = f.Seek(0, io.SeekEnd)
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+		}
+
+		err = follow(cliContext(), f, os.Stdout, viper.GetString("pid-file"))
+		if err != nil && err != context.Canceled {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+// follow copies everything that is written to f to the output until the
+// context is done or the detached prox instance has stopped.
+func follow(ctx context.Context, f io.Reader, output io.Writer, pidFile string) error {
+	for {
+		n, err := io.Copy(output, f)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			continue
+		}
+
+		pid, err := readPIDFile(pidFile)
+		if err != nil || !processExists(pid) {
+			// prox has stopped so we only need to copy the last output
+			_, err = io.Copy(output, f)
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fgrosse/prox"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// addClientFlags adds the flags that select the prox instance a command
+// connects to.
+func addClientFlags(flags *pflag.FlagSet) {
+	flags.StringP("socket", "s", DefaultSocketPath, "path of unix socket file to connect to")
+	flags.String("addr", "", "TCP address of a remote prox instance (see prox start --remote) instead of the unix socket")
+	flags.String("token", "", "token of the remote prox instance (default $PROX_TOKEN)")
+	flags.String("tls-cert", "", "only connect to a remote prox instance that uses this certificate")
+	flags.String("known-hosts", defaultKnownHostsFile(), "file with the certificate fingerprints of remote prox instances that were trusted on the first connection")
+}
+
+// defaultKnownHostsFile returns the path of the known hosts file in the home
+// directory of the user.
+func defaultKnownHostsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".prox", "known_hosts")
+}
+
+// newClient connects to the prox instance that was selected via the flags of
+// addClientFlags.
+func newClient() (*prox.Client, error) {
+	debug := viper.GetBool("verbose")
+	addr := viper.GetString("addr")
+	if addr == "" {
+		return prox.NewClient(viper.GetString("socket"), debug)
+	}
+
+	return prox.NewRemoteClient(addr, prox.RemoteOptions{
+		Token:          viper.GetString("token"),
+		CertFile:       viper.GetString("tls-cert"),
+		KnownHostsFile: viper.GetString("known-hosts"),
+	}, debug)
+}
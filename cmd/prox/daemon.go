@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPIDFile = ".prox.pid" // hidden file in current PWD
+	DefaultLogFile = ".prox.log" // hidden file in current PWD
+)
+
+// detachTimeout is the maximum time detach waits for the background process to
+// write its pidfile and to accept connections on its socket.
+const detachTimeout = 10 * time.Second
+
+// detach starts prox again as background process that is not attached to the
+// current terminal. All output of the background process is written to the
+// log file. The function returns once the background process has written its
+// pidfile (and its socket accepts connections) or if it failed to start. If
+// this takes longer than detachTimeout, the background process is killed.
+func detach(logFile, pidFile, socketPath string) (pid int, err error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to determine path of prox executable")
+	}
+
+	if pid, err := readPIDFile(pidFile); err == nil && processExists(pid) {
+		return 0, errors.Errorf("prox is already running in the background (PID %d)", pid)
+	}
+
+	// remove a stale pidfile so we do not mistake it for the pidfile of the
+	// new background process below
+	err = os.Remove(pidFile)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, errors.Wrap(err, "failed to remove stale pidfile")
+	}
+
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to open log file")
+	}
+	defer f.Close()
+
+	// later flags overwrite earlier ones so we do not need to remove --detach
+	args := append(os.Args[1:], "--detach=false", "--daemon")
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = f
+	cmd.Stderr = f
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true} // do not receive signals of the terminal
+
+	err = cmd.Start()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to start background process")
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	timeout := time.After(detachTimeout)
+	for {
+		select {
+		case err := <-exited:
+			return 0, errors.Errorf("background process has stopped (%v). See %s for details", err, logFile)
+		case <-timeout:
+			// give prox the chance to interrupt the processes it has started
+			cmd.Process.Signal(syscall.SIGTERM)
+			select {
+			case <-exited:
+			case <-time.After(detachTimeout):
+				cmd.Process.Kill()
+				<-exited
+			}
+			return 0, errors.Errorf("background process did not start within %v. See %s for details", detachTimeout, logFile)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		pid, err := readPIDFile(pidFile)
+		if err != nil || pid != cmd.Process.Pid {
+			continue
+		}
+
+		if socketPath != "" && !socketAccepts(socketPath) {
+			continue
+		}
+
+		return pid, nil
+	}
+}
+
+// socketAccepts returns true if the unix socket accepts connections.
+func socketAccepts(path string) bool {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return false
+	}
+
+	conn.Close()
+	return true
+}
+
+// writePIDFile writes the PID of the current process to the given file. The
+// returned function removes the file again.
+func writePIDFile(path string) (remove func(), err error) {
+	err = ioutil.WriteFile(path, []byte(fmt.Sprintln(os.Getpid())), 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write pidfile")
+	}
+
+	return func() { os.Remove(path) }, nil
+}
+
+// readPIDFile returns the PID that was written to the given file.
+func readPIDFile(path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid pidfile %q", path)
+	}
+
+	return pid, nil
+}
+
+// processExists returns true if there is a running process with the given PID.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
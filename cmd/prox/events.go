@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fgrosse/prox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(eventsCmd)
+
+	var types []string
+	for _, t := range prox.EventTypes() {
+		types = append(types, string(t))
+	}
+
+	flags := eventsCmd.Flags()
+	addClientFlags(flags)
+	flags.StringSliceP("process", "p", nil, "only show events of the given processes")
+	flags.StringSliceP("type", "t", nil, fmt.Sprintf("only show events of the given types (%s)", strings.Join(types, ", ")))
+}
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Stream lifecycle events of the running processes as JSON",
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		var filter prox.EventFilter
+		filter.Processes = viper.GetStringSlice("process")
+		for _, t := range viper.GetStringSlice("type") {
+			if !isEventType(t) {
+				logger.Fatal(fmt.Sprintf("Unknown event type %q", t))
+			}
+			filter.Types = append(filter.Types, prox.EventType(t))
+		}
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		ctx := cliContext()
+		events, err := c.Events(ctx, filter)
+		switch {
+		case err == context.Canceled:
+			return
+		case err != nil:
+			logger.Fatal(err.Error())
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		for e := range events {
+			err = enc.Encode(e)
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+		}
+	},
+}
+
+func isEventType(s string) bool {
+	for _, t := range prox.EventTypes() {
+		if string(t) == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"text/tabwriter"
+	"time"
 
-	"github.com/fgrosse/prox"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -13,31 +15,53 @@ func init() {
 	cmd.AddCommand(lsCmd)
 
 	flags := lsCmd.Flags()
-	flags.StringP("socket", "s", DefaultSocketPath, "path of unix socket file to connect to")
+	addClientFlags(flags)
 }
 
 var lsCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List information about currently running processes",
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := cliContext()
-		debug := viper.GetBool("verbose")
-		logger := prox.NewLogger(os.Stderr, debug)
-
-		socketPath, err := cmd.Flags().GetString("socket")
-		if err != nil {
-			logger.Fatal("Failed to get --socket flag: " + err.Error())
-		}
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
 
-		c, err := prox.NewClient(socketPath, debug)
+		ctx := cliContext()
+		c, err := newClient()
 		if err != nil {
 			logger.Fatal(err.Error())
 		}
 		defer c.Close()
 
-		err = c.List(ctx, os.Stdout)
-		if err != nil && err != context.Canceled {
+		infos, err := c.List(ctx)
+		switch {
+		case err == context.Canceled:
+			return
+		case err != nil:
 			logger.Fatal(err.Error())
 		}
+
+		w := tabwriter.NewWriter(os.Stdout, 8, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATE\tPID\tUPTIME\tRUNS\tNEXT RUN")
+
+		for _, inf := range infos {
+			pid, uptime := "-", "-"
+			if inf.PID >= 0 {
+				pid = fmt.Sprint(inf.PID)
+				uptime = inf.Uptime.Round(time.Second).String()
+			}
+
+			runs, next := "-", "-"
+			if !inf.NextRun.IsZero() {
+				runs = fmt.Sprint(inf.Runs)
+				next = fmt.Sprintf("in %v (%s)",
+					time.Until(inf.NextRun).Round(time.Second),
+					inf.NextRun.Format("2006-01-02 15:04:05"),
+				)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", inf.Name, inf.State, pid, uptime, runs, next)
+		}
+
+		w.Flush()
 	},
 }
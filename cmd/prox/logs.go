@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fgrosse/prox"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// DefaultLogDir is the directory in which the output of all processes is
+// persisted.
+const DefaultLogDir = ".prox-logs" // hidden directory in current PWD
+
+func init() {
+	cmd.AddCommand(logsCmd)
+
+	flags := logsCmd.Flags()
+	flags.String("log-dir", DefaultLogDir, "directory in which prox has persisted the output of the processes")
+	flags.String("since", "", `only show output since this time (e.g. "10m" or "2018-12-09T15:04:05+01:00")`)
+	flags.String("until", "", `only show output until this time (e.g. "5m" or "2018-12-09T15:04:05+01:00")`)
+	flags.BoolP("follow", "f", false, "wait for new output after printing the persisted output")
+	flags.Bool("raw", false, "print the output exactly as the process has emitted it instead of the formatted output")
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <process>",
+	Short: "Show the persisted output of a process",
+	Long: `Show the persisted output of a process from the log directory.
+
+The output is read from the files prox has written to the log directory. This works regardless of whether prox is still running.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		if len(args) != 1 {
+			logger.Error("prox logs requires exactly one argument\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		now := time.Now()
+		since, err := parseLogTime(viper.GetString("since"), now)
+		if err != nil {
+			logger.Fatal("Invalid --since: " + err.Error())
+		}
+
+		until, err := parseLogTime(viper.GetString("until"), now)
+		if err != nil {
+			logger.Fatal("Invalid --until: " + err.Error())
+		}
+
+		opts := prox.LogOptions{
+			Since:  since,
+			Until:  until,
+			Raw:    viper.GetBool("raw"),
+			Follow: viper.GetBool("follow"),
+		}
+
+		err = prox.ReadLogs(cliContext(), viper.GetString("log-dir"), args[0], opts, os.Stdout)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+// parseLogTime parses either a duration that is relative to now or an absolute
+// time in RFC 3339 format. The zero time is returned for the empty string.
+func parseLogTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("%q is neither a duration (e.g. 10m) nor a time in RFC 3339 format", s)
+	}
+
+	return t, nil
+}
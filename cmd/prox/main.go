@@ -21,11 +21,16 @@ const (
 	StatusBadProcFile   = 3
 	StatusMissingArgs   = 4
 
-	DefaultSocketPath = ".prox.sock" // hidden file in current PWD
+	DefaultSocketPath = ".prox.sock"   // hidden file in current PWD
+	DefaultRemoteDir  = ".prox-remote" // certificate and token for remote clients
 )
 
 var logger *zap.Logger
 
+// exitCode is the exit code of prox once the command has returned and all of
+// its deferred functions (e.g. logger.Sync) have been called.
+var exitCode int
+
 var cmd = &cobra.Command{
 	Use:   "prox",
 	Short: "A process runner for Procfile-based applications",
@@ -54,12 +59,20 @@ func main() {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+
+	os.Exit(exitCode)
 }
 
 func cliContext() context.Context {
+	return signalContext(syscall.SIGALRM, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+}
+
+// signalContext returns a context that is canceled as soon as the process
+// receives any of the given signals.
+func signalContext(signals ...os.Signal) context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGALRM, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigs, signals...)
 
 	go func() {
 		<-sigs
@@ -126,3 +139,14 @@ func processes(env prox.Environment, procFileFlag string) ([]prox.Process, error
 	defer f.Close()
 	return parse(f, env)
 }
+
+// processPatterns returns the process names or patterns that were given as
+// arguments. If the --all flag is set, all processes are selected and the
+// arguments can only exclude processes (e.g. "!worker").
+func processPatterns(args []string) []string {
+	if viper.GetBool("all") {
+		return append([]string{prox.AllProcesses}, args...)
+	}
+
+	return args
+}
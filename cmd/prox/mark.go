@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fgrosse/prox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(markCmd)
+	cmd.AddCommand(unmarkCmd)
+
+	flags := markCmd.Flags()
+	addClientFlags(flags)
+	flags.Bool("all", false, "select all processes (use \"!name\" arguments to exclude some of them)")
+	flags.String("style", string(prox.MarkGutter), `how the output is highlighted ("gutter" or "background")`)
+	flags.StringArray("where", nil, `only mark lines whose field matches "key=value" or "key=/regex/" (can be repeated)`)
+
+	flags = unmarkCmd.Flags()
+	addClientFlags(flags)
+	flags.Bool("all", false, "select all processes (use \"!name\" arguments to exclude some of them)")
+}
+
+var markCmd = &cobra.Command{
+	Use:   "mark <process> [process-2] … [process-N]",
+	Short: "Highlight the output of one or many processes in the terminal of a running prox instance",
+	Long: `Highlight the output of one or many processes in the terminal of a running prox instance.
+
+With --style gutter (default) a heavy bar (┃) is printed instead of the separator
+after the process name. With --style background the messages are printed on the
+background color of the process. Use --where to only mark lines whose fields
+match (e.g. --where host=foobar). The mark is kept until "prox unmark" is used.
+
+Processes can be selected by name, by glob patterns like 'api-*' or via --all.
+Arguments that start with "!" exclude processes (e.g. prox mark --all '!db').`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		patterns := processPatterns(args)
+		if len(patterns) == 0 {
+			logger.Error("prox mark requires at least one argument or --all\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		m := prox.Mark{Style: prox.MarkStyle(viper.GetString("style"))}
+		m.Where, _ = cmd.Flags().GetStringArray("where")
+
+		names, err := c.Mark(cliContext(), m, patterns...)
+		if err != nil && err != context.Canceled {
+			logger.Fatal(err.Error())
+		}
+
+		if len(names) > 0 {
+			fmt.Println("Marked", strings.Join(names, ", "))
+		}
+	},
+}
+
+var unmarkCmd = &cobra.Command{
+	Use:   "unmark <process> [process-2] … [process-N]",
+	Short: "Remove the marks of one or many processes of a running prox instance",
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		patterns := processPatterns(args)
+		if len(patterns) == 0 {
+			logger.Error("prox unmark requires at least one argument or --all\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		names, err := c.Unmark(cliContext(), patterns...)
+		if err != nil && err != context.Canceled {
+			logger.Fatal(err.Error())
+		}
+
+		if len(names) > 0 {
+			fmt.Println("Unmarked", strings.Join(names, ", "))
+		}
+	},
+}
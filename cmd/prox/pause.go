@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(pauseCmd)
+	cmd.AddCommand(resumeCmd)
+
+	for _, c := range []*cobra.Command{pauseCmd, resumeCmd} {
+		flags := c.Flags()
+		addClientFlags(flags)
+		flags.Bool("all", false, "select all processes (use \"!name\" arguments to exclude some of them)")
+	}
+}
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause <process> [process-2] … [process-N]",
+	Short: "Freeze one or many processes of a running prox instance",
+	Long: `Freeze one or many processes of a running prox instance.
+
+The whole process group of each process receives SIGSTOP so the process and all
+of its children stop executing until they are continued via "prox resume". The
+timeouts of a paused process are suspended so it is not aborted while it is
+paused.
+
+Processes can be selected by name, by glob patterns like 'api-*' or via --all.
+Arguments that start with "!" exclude processes (e.g. prox pause --all '!db').`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		patterns := processPatterns(args)
+		if len(patterns) == 0 {
+			logger.Error("prox pause requires at least one argument or --all\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		names, err := c.PauseProcesses(cliContext(), patterns...)
+		if err != nil && err != context.Canceled {
+			logger.Fatal(err.Error())
+		}
+
+		if len(names) > 0 {
+			fmt.Println("Paused", strings.Join(names, ", "))
+		}
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <process> [process-2] … [process-N]",
+	Short: "Continue one or many processes that were paused via prox pause",
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		patterns := processPatterns(args)
+		if len(patterns) == 0 {
+			logger.Error("prox resume requires at least one argument or --all\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		names, err := c.ResumeProcesses(cliContext(), patterns...)
+		if err != nil && err != context.Canceled {
+			logger.Fatal(err.Error())
+		}
+
+		if len(names) > 0 {
+			fmt.Println("Resumed", strings.Join(names, ", "))
+		}
+	},
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(reloadCmd)
+
+	flags := reloadCmd.Flags()
+	addClientFlags(flags)
+}
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the Proxfile or Procfile and .env and restart only the processes that have changed",
+	Long: `Reload the Proxfile or Procfile and .env of a running prox instance.
+
+Processes that have been added are started and processes that have been removed
+are stopped. Processes whose script, environment or output configuration have
+changed are restarted. All other processes keep running.
+
+Sending SIGHUP to the prox process has the same effect.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		ctx := cliContext()
+		diff, err := c.Reload(ctx)
+		switch {
+		case err == context.Canceled:
+			return
+		case err != nil:
+			logger.Fatal(err.Error())
+		}
+
+		fmt.Println(diff)
+	},
+}
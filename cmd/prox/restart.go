@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(restartCmd)
+
+	flags := restartCmd.Flags()
+	addClientFlags(flags)
+	flags.Bool("all", false, "select all processes (use \"!name\" arguments to exclude some of them)")
+}
+
+var restartCmd = &cobra.Command{
+	Use:   "restart <process> [process-2] … [process-N]",
+	Short: "Restart one or many processes of a running prox instance",
+	Long: `Restart one or many processes of a running prox instance.
+
+Processes can be selected by name, by glob patterns like 'api-*' or via --all.
+Arguments that start with "!" exclude processes (e.g. prox restart --all '!db').`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		patterns := processPatterns(args)
+		if len(patterns) == 0 {
+			logger.Error("prox restart requires at least one argument or --all\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		names, err := c.RestartProcesses(cliContext(), patterns...)
+		if err != nil && err != context.Canceled {
+			logger.Fatal(err.Error())
+		}
+
+		if len(names) > 0 {
+			fmt.Println("Restarted", strings.Join(names, ", "))
+		}
+	},
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/fgrosse/prox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(runCmd)
+
+	flags := runCmd.Flags()
+	flags.SetInterspersed(false) // all flags after the command belong to the command
+	flags.StringP("env", "e", ".env", "path to the env file")
+	flags.StringP("procfile", "f", "", `path to the Proxfile or Procfile (default "Proxfile" or "Procfile")`)
+	flags.String("as", "", "run the command with the environment of this process from the Proxfile or Procfile")
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [--as <process>] -- <command> [args…]",
+	Short: "Run a one-off command with the environment of the stack or of a single process",
+	Long: `Run a one-off command with the environment of the stack or of a single process.
+
+The command receives the environment from the env file (see --env) or, if --as
+is used, the exact environment of that process including its variables from the
+Proxfile. Like all processes of prox it runs in the current working directory.
+None of the processes of the Proxfile or Procfile are started.
+
+prox exits with the exit code of the command.
+
+Example:
+  prox run -- rake db:migrate
+  prox run --as api -- ./debug.sh`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		if len(args) == 0 {
+			logger.Error("prox run requires a command\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		env, err := environment(viper.GetString("env"))
+		if err != nil {
+			logger.Error("Failed to parse env file: " + err.Error())
+			os.Exit(StatusBadEnvFile)
+		}
+
+		if name := viper.GetString("as"); name != "" {
+			env = processEnvironment(env, name)
+		}
+
+		exitCode = runCommand(env, args)
+	},
+}
+
+// processEnvironment returns the environment of the process with the given
+// name from the Proxfile or Procfile.
+func processEnvironment(env prox.Environment, name string) prox.Environment {
+	pp, err := processes(env, viper.GetString("procfile"))
+	if err != nil {
+		logger.Error("Failed to parse Procfile: " + err.Error())
+		os.Exit(StatusBadProcFile)
+	}
+
+	for _, p := range pp {
+		if p.Name == name {
+			return p.Env
+		}
+	}
+
+	logger.Error(fmt.Sprintf("No such process %q. Use `prox show --all` to see a list of all available processes", name))
+	os.Exit(StatusBadProcFile)
+	return nil
+}
+
+// runCommand runs the command in the foreground and returns its exit code.
+// Signals that are sent to prox are forwarded to the command. An interrupt of
+// the terminal is not forwarded because the command receives it already.
+func runCommand(env prox.Environment, args []string) int {
+	c := exec.Command(args[0], args[1:]...)
+	c.Env = env.List()
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	err := c.Start()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	go func() {
+		for sig := range sigs {
+			if sig != syscall.SIGINT {
+				c.Process.Signal(sig)
+			}
+		}
+	}()
+
+	err = c.Wait()
+	if err == nil {
+		return 0
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		logger.Fatal(err.Error())
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if ok && status.Signaled() {
+		// the same exit code a shell uses for commands that were killed
+		return 128 + int(status.Signal())
+	}
+
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fgrosse/prox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(signalCmd)
+
+	flags := signalCmd.Flags()
+	addClientFlags(flags)
+	flags.Bool("all", false, "select all processes (use \"!name\" arguments to exclude some of them)")
+	flags.BoolP("group", "g", false, "send the signal to the whole process group (i.e. also to all child processes)")
+}
+
+var signalCmd = &cobra.Command{
+	Use:   "signal <process> [process-2] … [process-N] <signal>",
+	Short: "Send a signal (e.g. SIGUSR1, HUP or 3) to one or many running processes",
+	Long: `Send a signal (e.g. SIGUSR1, HUP or 3) to one or many running processes.
+
+Processes can be selected by name, by glob patterns like 'api-*' or via --all.
+Arguments that start with "!" exclude processes (e.g. prox signal --all '!db' HUP).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		if len(args) == 0 || len(processPatterns(args[:len(args)-1])) == 0 {
+			logger.Error("prox signal requires the process names (or --all) and the signal as arguments\n")
+			fmt.Println(cmd.UsageString())
+			os.Exit(StatusMissingArgs)
+		}
+
+		signal := args[len(args)-1]
+		patterns := processPatterns(args[:len(args)-1])
+
+		_, err := prox.ParseSignal(signal)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		c, err := newClient()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer c.Close()
+
+		ctx := cliContext()
+		_, err = c.Signal(ctx, patterns, signal, viper.GetBool("group"))
+		if err != nil && err != context.Canceled {
+			logger.Fatal(err.Error())
+		}
+	},
+}
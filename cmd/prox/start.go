@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/fgrosse/prox"
 	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -19,6 +23,19 @@ func init() {
 	flags.StringP("procfile", "f", "", `path to the Proxfile or Procfile (default "Proxfile" or "Procfile")`)
 	flags.StringP("socket", "s", DefaultSocketPath, "path of the temporary unix socket file that clients can use to establish a connection")
 	flags.Bool("no-socket", false, "do not create a unix socket for prox clients")
+	flags.String("http", "", `additionally serve the HTTP API on this loopback address (e.g. "localhost:5555")`)
+	flags.String("remote", "", `additionally serve prox clients on other machines via TLS on this TCP address (e.g. ":7777")`)
+	flags.String("remote-dir", DefaultRemoteDir, "directory of the TLS certificate and the token for remote clients (created on the first run)")
+	flags.Int("history-lines", prox.DefaultHistoryLines, "number of recent output lines per process that are kept for prox tail")
+	flags.Int("history-bytes", prox.DefaultHistoryBytes, "maximum size in bytes of the recent output per process that is kept for prox tail")
+	flags.String("log-dir", DefaultLogDir, "directory in which the output of each process is persisted (disabled if empty)")
+	flags.Int64("log-max-size", prox.DefaultLogFileSize, "maximum size in bytes of a log file in the log directory before it is rotated")
+	flags.Int("log-max-files", prox.DefaultLogFileCount, "number of rotated log files that are kept per process")
+	flags.BoolP("detach", "d", false, "run all processes in the background and write their output to the log file")
+	flags.String("log-file", DefaultLogFile, "path of the log file that receives the output if --detach is used")
+	flags.String("pid-file", DefaultPIDFile, "path of the pidfile that is written if --detach is used")
+	flags.Bool("daemon", false, "run as background process started via --detach")
+	flags.MarkHidden("daemon")
 }
 
 var startCmd = &cobra.Command{
@@ -31,7 +48,9 @@ func run(cmd *cobra.Command, _ []string) {
 	viper.BindPFlags(cmd.Flags())
 	defer logger.Sync()
 
-	ctx := cliContext()
+	// SIGHUP is not part of the signals that stop the stack because we use it
+	// to reload the configuration instead.
+	ctx := signalContext(syscall.SIGALRM, syscall.SIGINT, syscall.SIGTERM)
 	debug := viper.GetBool("verbose")
 
 	env, err := environment(viper.GetString("env"))
@@ -54,10 +73,30 @@ func run(cmd *cobra.Command, _ []string) {
 		os.Exit(StatusBadProcFile)
 	}
 
+	if viper.GetBool("detach") {
+		runDetached()
+		return
+	}
+
+	removePIDFile := func() {}
+	if viper.GetBool("daemon") {
+		removePIDFile, err = writePIDFile(viper.GetString("pid-file"))
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+	}
+
 	var done func() error
 	var executor interface {
 		Run(context.Context, []prox.Process) error
+		Reload([]prox.Process) (prox.ReloadDiff, error)
 		DisableColoredOutput()
+		SetHistoryLimit(lines, bytes int)
+		SetLogDir(dir string, maxSize int64, maxFiles int)
+	}
+
+	if viper.GetBool("no-socket") && viper.GetString("remote") != "" {
+		logger.Fatal("prox cannot serve remote clients if --no-socket is used")
 	}
 
 	if viper.GetBool("no-socket") {
@@ -67,6 +106,13 @@ func run(cmd *cobra.Command, _ []string) {
 	} else {
 		socketPath := viper.GetString("socket")
 		es := prox.NewExecutorServer(socketPath, debug)
+		es.SetProcessLoader(loadProcesses)
+		if addr := viper.GetString("http"); addr != "" {
+			es.SetHTTPAddress(addr)
+		}
+		if addr := viper.GetString("remote"); addr != "" {
+			es.SetRemoteAddress(addr, viper.GetString("remote-dir"))
+		}
 		done = es.Close
 		executor = es
 	}
@@ -75,11 +121,77 @@ func run(cmd *cobra.Command, _ []string) {
 		executor.DisableColoredOutput()
 	}
 
+	executor.SetHistoryLimit(viper.GetInt("history-lines"), viper.GetInt("history-bytes"))
+	if dir := viper.GetString("log-dir"); dir != "" {
+		executor.SetLogDir(dir, viper.GetInt64("log-max-size"), viper.GetInt("log-max-files"))
+	}
+
+	go reloadOnSIGHUP(ctx, executor.Reload)
+
 	err = executor.Run(ctx, pp)
 	done() // always close the executor/server regardless of any error
+	removePIDFile()
 
 	if err != nil {
 		// the error was logged by the executor already
 		os.Exit(StatusFailedProcess)
 	}
 }
+
+// runDetached starts prox in the background and returns once it is running.
+func runDetached() {
+	var socketPath string
+	if !viper.GetBool("no-socket") {
+		socketPath = viper.GetString("socket")
+	}
+
+	logFile := viper.GetString("log-file")
+	pid, err := detach(logFile, viper.GetString("pid-file"), socketPath)
+	if err != nil {
+		logger.Error("Failed to start prox in the background: " + err.Error())
+		os.Exit(StatusFailedProcess)
+	}
+
+	fmt.Printf("prox is running in the background (PID %d)\n", pid)
+	fmt.Printf("Its output is written to %s. Use `prox attach-output` to follow it and `prox stop` to stop all processes.\n", logFile)
+}
+
+// loadProcesses parses the env file and the Proxfile or Procfile again to
+// reload the configuration of a running stack.
+func loadProcesses() ([]prox.Process, error) {
+	env, err := environment(viper.GetString("env"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse env file")
+	}
+
+	pp, err := processes(env, viper.GetString("procfile"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse Procfile")
+	}
+
+	return pp, nil
+}
+
+// reloadOnSIGHUP reloads the configuration each time the process receives a
+// SIGHUP until the context is done.
+func reloadOnSIGHUP(ctx context.Context, reload func([]prox.Process) (prox.ReloadDiff, error)) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+			logger.Debug("Received SIGHUP: reloading configuration")
+			pp, err := loadProcesses()
+			if err == nil {
+				_, err = reload(pp)
+			}
+			if err != nil {
+				logger.Error("Failed to reload configuration: " + err.Error())
+			}
+		}
+	}
+}
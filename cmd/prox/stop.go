@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	cmd.AddCommand(stopCmd)
+
+	flags := stopCmd.Flags()
+	addClientFlags(flags)
+	flags.Bool("all", false, "select all processes (use \"!name\" arguments to exclude some of them)")
+	flags.String("pid-file", DefaultPIDFile, "path of the pidfile that is used if prox cannot be reached via its socket")
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop [process-1] … [process-N]",
+	Short: "Gracefully stop all or only the given processes of a running prox instance",
+	Long: `Gracefully stop all processes of a running prox instance (e.g. started via --detach).
+
+If process names are given, only these processes are stopped while the rest of
+the stack keeps running. They can be started again via "prox restart".
+Processes can also be selected by glob patterns like 'api-*' or via --all and
+arguments that start with "!" exclude processes (e.g. prox stop --all '!db').`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		defer logger.Sync()
+
+		ctx := cliContext()
+
+		if patterns := processPatterns(args); len(patterns) > 0 {
+			stopProcesses(ctx, patterns)
+			return
+		}
+
+		c, err := newClient()
+		if err != nil && viper.GetString("addr") != "" {
+			logger.Fatal(err.Error())
+		}
+		if err != nil {
+			// maybe prox was started without a socket
+			err = stopViaPIDFile(ctx, viper.GetString("pid-file"))
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+			return
+		}
+		defer c.Close()
+
+		err = c.Shutdown(ctx)
+		switch {
+		case err == context.Canceled:
+			return
+		case err != nil:
+			logger.Fatal(err.Error())
+		}
+
+		fmt.Println("All processes have been stopped")
+	},
+}
+
+// stopProcesses stops only the processes that are selected by the patterns.
+func stopProcesses(ctx context.Context, patterns []string) {
+	c, err := newClient()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+	defer c.Close()
+
+	names, err := c.StopProcesses(ctx, patterns...)
+	if err != nil && err != context.Canceled {
+		logger.Fatal(err.Error())
+	}
+
+	if len(names) > 0 {
+		fmt.Println("Stopped", strings.Join(names, ", "))
+	}
+}
+
+// stopViaPIDFile sends SIGTERM to the prox process in the given pidfile and
+// waits until it has finished.
+func stopViaPIDFile(ctx context.Context, path string) error {
+	pid, err := readPIDFile(path)
+	if err != nil {
+		return fmt.Errorf("prox does not seem to be running: %v", err)
+	}
+
+	err = syscall.Kill(pid, syscall.SIGTERM)
+	if err != nil {
+		return fmt.Errorf("failed to stop prox (PID %d): %v", pid, err)
+	}
+
+	for processExists(pid) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	fmt.Println("All processes have been stopped")
+	return nil
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/fgrosse/prox"
 	"github.com/spf13/cobra"
@@ -13,33 +18,208 @@ func init() {
 	cmd.AddCommand(tailCmd)
 
 	flags := tailCmd.Flags()
-	flags.StringP("socket", "s", DefaultSocketPath, "path of unix socket file to connect to")
+	addClientFlags(flags)
+	flags.Bool("all", false, "select all processes (use \"!name\" arguments to exclude some of them)")
+	flags.IntP("lines", "n", 0, "print the last n lines of output before following it")
+	flags.String("grep", "", "only print lines that match this regular expression")
+	flags.String("level", "", `only print lines with at least this level (e.g. "warn")`)
+	flags.StringArray("where", nil, `only print lines whose field matches "key=value" or "key=/regex/" (can be repeated)`)
+	flags.Bool("no-color", false, "disable colored output")
+	flags.Bool("raw", false, "print the output exactly as it was emitted by the processes")
+	flags.Bool("json", false, "print every line of output as JSON object including its decoded structured log message")
+	flags.Bool("follow-restarts", false, "wait until prox is restarted instead of exiting when it was stopped and resume following the output")
+	flags.Bool("no-follow", false, "print the recent output and exit instead of following it (all recent lines unless --lines is set)")
 }
 
 var tailCmd = &cobra.Command{
 	Use:   "tail <process> [process-2] … [process-N]",
 	Short: "Follow the log output of one or many running processes",
+	Long: `Follow the log output of one or many running processes.
+
+Processes can be selected by name, by glob patterns like 'api-*' or via --all.
+Arguments that start with "!" exclude processes (e.g. prox tail --all '!worker').
+Processes that are added later (e.g. via "prox reload") are followed as well
+if they match the patterns.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlags(cmd.Flags())
 		defer logger.Sync()
 
-		debug := viper.GetBool("verbose")
-		socketPath := viper.GetString("socket")
-
-		if len(args) == 0 {
-			logger.Fatal("prox tail requires at least one argument")
+		patterns := processPatterns(args)
+		if len(patterns) == 0 {
+			logger.Fatal("prox tail requires at least one argument or --all")
 		}
 
-		c, err := prox.NewClient(socketPath, debug)
+		c, err := newClient()
 		if err != nil {
 			logger.Fatal(err.Error())
 		}
 		defer c.Close()
 
+		opts := prox.TailOptions{
+			History:  viper.GetInt("lines"),
+			NoFollow: viper.GetBool("no-follow"),
+			Grep:     viper.GetString("grep"),
+			Level:    viper.GetString("level"),
+		}
+
+		opts.Where, _ = cmd.Flags().GetStringArray("where")
+
+		opts.FollowRestarts = viper.GetBool("follow-restarts")
+		if opts.NoFollow && !cmd.Flags().Changed("lines") {
+			opts.History = -1
+		}
+
+		r := &lineRenderer{
+			output:  os.Stdout,
+			json:    json.NewEncoder(os.Stdout),
+			noColor: viper.GetBool("no-color"),
+		}
+
+		switch {
+		case viper.GetBool("raw") && viper.GetBool("json"):
+			logger.Fatal("prox tail cannot use --raw and --json at the same time")
+		case viper.GetBool("raw"):
+			r.format = formatRaw
+		case viper.GetBool("json"):
+			r.format = formatJSON
+		}
+
 		ctx := cliContext()
-		err = c.Tail(ctx, args, os.Stdout)
-		if err != nil && err != context.Canceled {
+		if r.format == formatPrefixed {
+			r.prefixLength, err = longestProcessName(ctx, c)
+			switch {
+			case err == context.Canceled:
+				return
+			case err != nil:
+				logger.Fatal(err.Error())
+			}
+		}
+
+		lines, err := c.Tail(ctx, patterns, opts)
+		switch {
+		case err == context.Canceled:
+			return
+		case err != nil:
 			logger.Fatal(err.Error())
 		}
+
+		for l := range lines {
+			if l.Err != nil {
+				logger.Fatal(l.Err.Error())
+			}
+
+			err = r.render(l)
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+		}
 	},
 }
+
+// The output formats of prox tail.
+const (
+	formatPrefixed = ""     // prefixed with the process name and formatted like the prox output
+	formatRaw      = "raw"  // exactly as it was emitted by the processes
+	formatJSON     = "json" // every line as JSON encoded prox.OutputLine
+)
+
+// A lineRenderer prints the lines of prox tail in one of the output formats.
+type lineRenderer struct {
+	output       io.Writer
+	json         *json.Encoder
+	format       string
+	noColor      bool
+	prefixLength int
+}
+
+func (r *lineRenderer) render(l prox.LogLine) error {
+	if l.Gap != nil {
+		return r.gap(l.Gap.Disconnected, l.Gap.Reconnected)
+	}
+
+	if l.Dropped > 0 {
+		err := r.dropped(l.Dropped)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch r.format {
+	case formatRaw:
+		_, err := fmt.Fprintln(r.output, l.Line)
+		return err
+	case formatJSON:
+		return r.json.Encode(l.OutputLine)
+	}
+
+	prefix := l.Process
+	if n := r.prefixLength - len(prefix); n > 0 {
+		prefix += strings.Repeat(" ", n)
+	}
+
+	prefix += " │ "
+	msg := l.Format()
+	if !r.noColor {
+		prefix = prox.Colorize(strings.TrimSuffix(l.Color, "-bold")+"-bold", prefix)
+		msg = prox.Colorize(l.TagColor, msg)
+	}
+
+	_, err := fmt.Fprintln(r.output, prefix+msg)
+	return err
+}
+
+// gap prints a separator for the time in which prox tail was disconnected.
+// Only the prefixed output contains the separator so the other formats can
+// still be processed by other tools.
+func (r *lineRenderer) gap(from, to time.Time) error {
+	if r.format != formatPrefixed {
+		return nil
+	}
+
+	const timeFormat = "15:04:05"
+	sep := fmt.Sprintf("──── disconnected from %s to %s (%s) ────",
+		from.Format(timeFormat), to.Format(timeFormat), to.Sub(from).Round(time.Second),
+	)
+
+	if !r.noColor {
+		sep = prox.Colorize("white-bold", sep)
+	}
+
+	_, err := fmt.Fprintln(r.output, sep)
+	return err
+}
+
+// dropped prints a separator for lines that prox has dropped because prox tail
+// was too slow to receive them.
+func (r *lineRenderer) dropped(n int) error {
+	if r.format != formatPrefixed {
+		return nil
+	}
+
+	sep := fmt.Sprintf("──── %d lines dropped (prox tail was too slow) ────", n)
+	if !r.noColor {
+		sep = prox.Colorize("white-bold", sep)
+	}
+
+	_, err := fmt.Fprintln(r.output, sep)
+	return err
+}
+
+// longestProcessName returns the length of the longest name of all processes
+// of the server so the prefixes of all lines are aligned like in the output of
+// prox itself.
+func longestProcessName(ctx context.Context, c *prox.Client) (int, error) {
+	infos, err := c.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 8
+	for _, inf := range infos {
+		if len(inf.Name) > n {
+			n = len(inf.Name)
+		}
+	}
+
+	return n, nil
+}
@@ -79,6 +79,67 @@ func (p *colorPalette) next() color {
 	return c
 }
 
+// colorName returns the name of a color of the palette (e.g. "cyan") or an
+// empty string if c is no such color.
+func colorName(c color) string {
+	switch c {
+	case colorRed:
+		return "red"
+	case colorGreen:
+		return "green"
+	case colorYellow:
+		return "yellow"
+	case colorBlue:
+		return "blue"
+	case colorMagenta:
+		return "magenta"
+	case colorCyan:
+		return "cyan"
+	case colorWhite:
+		return "white"
+	default:
+		return ""
+	}
+}
+
+// background returns the background variant of a color of the palette or
+// colorNone if c is no such color.
+func background(c color) color {
+	if colorName(c) == "" {
+		return colorNone
+	}
+
+	return color(strings.Replace(string(c), "[3", "[4", 1))
+}
+
+// colorSpec returns the configuration of a color (e.g. "red-bold") which can be
+// parsed via parseColor(…) or an empty string if c is no such color.
+func colorSpec(c color) string {
+	bold := strings.HasSuffix(string(c), string(colorBold))
+	name := colorName(color(strings.TrimSuffix(string(c), string(colorBold))))
+	if name == "" {
+		return ""
+	}
+
+	if bold {
+		name += "-bold"
+	}
+
+	return name
+}
+
+// Colorize wraps s in the ANSI escape codes of a color like "cyan" or
+// "red-bold" (see OutputLine.Color and OutputLine.TagColor). If the color is
+// unknown, s is returned unchanged.
+func Colorize(spec, s string) string {
+	c := parseColor(spec)
+	if colorSpec(c) == "" {
+		return s
+	}
+
+	return colored(c, s)
+}
+
 func colored(c color, s string) string {
 	return fmt.Sprint(c, s, colorDefault)
 }
@@ -61,3 +61,15 @@ var _ = Describe("parseColor", func() {
 		}
 	})
 })
+
+var _ = Describe("Colorize", func() {
+	It("should wrap the string in the escape codes of the color", func() {
+		Expect(Colorize("cyan", "foo")).To(Equal(string(colorCyan) + "foo" + string(colorDefault)))
+		Expect(Colorize("red-bold", "foo")).To(Equal(string(colorRed+colorBold) + "foo" + string(colorDefault)))
+	})
+
+	It("should not change the string if the color is unknown", func() {
+		Expect(Colorize("", "foo")).To(Equal("foo"))
+		Expect(Colorize("pink", "foo")).To(Equal("foo"))
+	})
+})
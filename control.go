@@ -0,0 +1,217 @@
+package prox
+
+import (
+	"fmt"
+	"sort"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// A noSuchProcessError is returned if there is no process with a given name.
+type noSuchProcessError string
+
+func (e noSuchProcessError) Error() string {
+	return fmt.Sprintf("no such process %q", string(e))
+}
+
+// A processStateError is returned if a process cannot be controlled because
+// of its current state (e.g. it cannot be started because it is running).
+type processStateError struct {
+	msg string
+}
+
+func (e processStateError) Error() string {
+	return e.msg
+}
+
+// StartProcess starts a process that is configured but currently not running
+// (e.g. because it was stopped via StopProcess or it has finished already).
+// Scheduled processes are started immediately in addition to their schedule.
+func (e *Executor) StartProcess(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	conf, err := e.controllable(name)
+	if err != nil {
+		return err
+	}
+
+	if _, running := e.running[name]; running {
+		return processStateError{fmt.Sprintf("process %q is already running", name)}
+	}
+
+	e.logger.Info("Starting process on request", zap.String("process_name", name))
+	if sp, ok := e.schedules[name]; ok {
+		e.start(e.scheduledRun(name, sp))
+		return nil
+	}
+
+	e.start(e.newProcess(conf, e.logger))
+	return nil
+}
+
+// StopProcess interrupts a running process. Its termination is not treated as
+// crash and thus it does not stop the other processes. Note that the Executor
+// finishes if there are no more running or scheduled processes.
+func (e *Executor) StopProcess(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err := e.controllable(name)
+	if err != nil {
+		return err
+	}
+
+	if _, running := e.running[name]; !running {
+		return processStateError{fmt.Sprintf("process %q is not running", name)}
+	}
+
+	e.logger.Info("Stopping process on request", zap.String("process_name", name))
+	delete(e.restarts, name)
+	e.stop(name)
+	return nil
+}
+
+// RestartProcess stops a running process and starts it again once it has
+// finished. If the process is not running, it is started immediately.
+func (e *Executor) RestartProcess(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	conf, err := e.controllable(name)
+	if err != nil {
+		return err
+	}
+
+	e.logger.Info("Restarting process on request", zap.String("process_name", name))
+	np := e.newProcess(conf, e.logger)
+	if _, running := e.running[name]; running {
+		e.restarts[name] = np
+		e.stop(name)
+		return nil
+	}
+
+	e.start(np)
+	return nil
+}
+
+// PauseProcess freezes a running process and all of its child processes by
+// sending SIGSTOP to its process group. While it is paused, its timeouts are
+// suspended so it is neither aborted because of its Timeout nor because it
+// has not become ready within its StartTimeout. Sending SIGSTOP or SIGCONT via
+// Executor.Signal pauses or resumes the process as well.
+func (e *Executor) PauseProcess(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sp, run, err := e.pausable(name)
+	if err != nil {
+		return err
+	}
+
+	if run.isPaused() {
+		return processStateError{fmt.Sprintf("process %q is already paused", name)}
+	}
+
+	err = sp.Signal(syscall.SIGSTOP, true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to pause process %q", name)
+	}
+
+	e.logger.Info("Pausing process on request", zap.String("process_name", name))
+	run.pause()
+	return nil
+}
+
+// ResumeProcess continues a process that was paused via PauseProcess by
+// sending SIGCONT to its process group. Its timeouts continue with the time
+// that was left when it was paused.
+func (e *Executor) ResumeProcess(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sp, run, err := e.pausable(name)
+	if err != nil {
+		return err
+	}
+
+	if !run.isPaused() {
+		return processStateError{fmt.Sprintf("process %q is not paused", name)}
+	}
+
+	err = sp.Signal(syscall.SIGCONT, true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resume process %q", name)
+	}
+
+	e.logger.Info("Resuming process on request", zap.String("process_name", name))
+	run.resume()
+	return nil
+}
+
+// pausable returns the running process with the given name and its current
+// run if it can be paused. The caller must hold e.mu.
+func (e *Executor) pausable(name string) (signaler, *processRun, error) {
+	_, err := e.controllable(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, running := e.running[name]
+	if !running {
+		return nil, nil, processStateError{fmt.Sprintf("process %q is not running", name)}
+	}
+
+	sp, ok := p.(signaler)
+	if !ok {
+		return nil, nil, processStateError{fmt.Sprintf("process %q cannot be paused", name)}
+	}
+
+	return sp, e.runs[name], nil
+}
+
+// controllable returns the configuration of the process with the given name
+// if the Executor is running. The caller must hold e.mu.
+func (e *Executor) controllable(name string) (Process, error) {
+	if e.ctx == nil || e.done || e.ctx.Err() != nil {
+		return Process{}, errors.New("executor is not running")
+	}
+
+	conf, ok := e.configs[name]
+	if !ok {
+		return Process{}, noSuchProcessError(name)
+	}
+
+	return conf, nil
+}
+
+// allProcesses returns the sorted names of all processes that are configured,
+// running or scheduled.
+func (e *Executor) allProcesses() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	seen := map[string]bool{}
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for name := range e.configs {
+		add(name)
+	}
+	for name := range e.running {
+		add(name)
+	}
+	for name := range e.schedules {
+		add(name)
+	}
+
+	sort.Strings(names)
+	return names
+}
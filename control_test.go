@@ -0,0 +1,185 @@
+package prox
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Executor process control", func() {
+	var (
+		executor *TestExecutor
+		runner   *countingRunner
+		done     chan error
+		cancel   func()
+	)
+
+	BeforeEach(func() {
+		executor = TestNewExecutor(GinkgoWriter)
+		executor.DisableColoredOutput()
+		runner = &countingRunner{starts: map[string]int{}}
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan error, 1)
+		go func() {
+			done <- executor.Executor.Run(ctx, []Process{
+				runner.process("p1"),
+				runner.process("p2"),
+			})
+		}()
+
+		Eventually(runner.Running).Should(ConsistOf("p1", "p2"))
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(Receive())
+	})
+
+	It("should stop and start a single process", func() {
+		Expect(executor.StopProcess("p1")).To(Succeed())
+		Eventually(runner.Running).Should(ConsistOf("p2"))
+		Eventually(func() ProcessState { return executor.Info("p1").State }).Should(Equal(StateStopped))
+		Consistently(done).ShouldNot(Receive(), "stopping a process should not stop the executor")
+
+		Expect(executor.StartProcess("p1")).To(Succeed())
+		Eventually(runner.Running).Should(ConsistOf("p1", "p2"))
+		Expect(executor.Info("p1").State).To(Equal(StateRunning))
+		Expect(runner.Starts("p1")).To(Equal(2))
+	})
+
+	It("should restart a running process", func() {
+		Expect(executor.RestartProcess("p2")).To(Succeed())
+		Eventually(func() int { return runner.Starts("p2") }).Should(Equal(2))
+		Eventually(runner.Running).Should(ConsistOf("p1", "p2"))
+		Expect(runner.Starts("p1")).To(Equal(1))
+	})
+
+	It("should return errors that can be mapped to error codes", func() {
+		err := executor.StartProcess("p1")
+		Expect(err).To(MatchError(`process "p1" is already running`))
+		Expect(errorCode(err)).To(Equal(CodeConflict))
+
+		err = executor.StopProcess("unknown")
+		Expect(err).To(MatchError(`no such process "unknown"`))
+		Expect(errorCode(err)).To(Equal(CodeNotFound))
+
+		Expect(executor.StopProcess("p1")).To(Succeed())
+		Eventually(runner.Running).Should(ConsistOf("p2"))
+
+		err = executor.StopProcess("p1")
+		Expect(err).To(MatchError(`process "p1" is not running`))
+		Expect(errorCode(err)).To(Equal(CodeConflict))
+	})
+	It("should not pause processes that do not support signals", func() {
+		err := executor.PauseProcess("p1")
+		Expect(err).To(MatchError(`process "p1" cannot be paused`))
+		Expect(errorCode(err)).To(Equal(CodeConflict))
+	})
+})
+
+var _ = Describe("Pausing processes", func() {
+	var (
+		executor *TestExecutor
+		done     chan struct{}
+		runErr   error
+		cancel   func()
+	)
+
+	// processState returns the state of a process from /proc (e.g. "S" for
+	// sleeping or "T" for stopped).
+	processState := func(pid int) string {
+		b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return ""
+		}
+
+		// the command name in parentheses is followed by the state
+		fields := strings.Fields(string(b[strings.LastIndex(string(b), ")")+1:]))
+		return fields[0]
+	}
+
+	BeforeEach(func() {
+		executor = TestNewExecutor(GinkgoWriter)
+		executor.DisableColoredOutput()
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+		go func() {
+			runErr = executor.Executor.Run(ctx, []Process{
+				{Name: "sleeper", Script: "sleep 10", Timeout: 300 * time.Millisecond},
+			})
+			close(done)
+		}()
+
+		Eventually(func() ProcessState { return executor.Info("sleeper").State }).Should(Equal(StateRunning))
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("should stop and continue the process", func() {
+		pid := executor.Info("sleeper").PID
+
+		Expect(executor.PauseProcess("sleeper")).To(Succeed())
+		Expect(executor.Info("sleeper").State).To(Equal(StatePaused))
+		Eventually(func() string { return processState(pid) }).Should(Equal("T"))
+
+		err := executor.PauseProcess("sleeper")
+		Expect(err).To(MatchError(`process "sleeper" is already paused`))
+		Expect(errorCode(err)).To(Equal(CodeConflict))
+
+		Expect(executor.ResumeProcess("sleeper")).To(Succeed())
+		Expect(executor.Info("sleeper").State).To(Equal(StateRunning))
+		Eventually(func() string { return processState(pid) }).Should(Equal("S"))
+
+		err = executor.ResumeProcess("sleeper")
+		Expect(err).To(MatchError(`process "sleeper" is not paused`))
+		Expect(errorCode(err)).To(Equal(CodeConflict))
+	})
+
+	It("should track processes that are stopped and continued via signals", func() {
+		pid := executor.Info("sleeper").PID
+
+		Expect(executor.PauseProcess("sleeper")).To(Succeed())
+		Eventually(func() string { return processState(pid) }).Should(Equal("T"))
+
+		Expect(executor.Signal("sleeper", syscall.SIGCONT, false)).To(Succeed())
+		Expect(executor.Info("sleeper").State).To(Equal(StateRunning))
+		Eventually(func() string { return processState(pid) }).Should(Equal("S"))
+
+		Expect(executor.Signal("sleeper", syscall.SIGSTOP, false)).To(Succeed())
+		Expect(executor.Info("sleeper").State).To(Equal(StatePaused))
+		Consistently(done, "500ms").ShouldNot(BeClosed(), "the timeout should be suspended")
+
+		Expect(executor.ResumeProcess("sleeper")).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(runErr).To(MatchError(ContainSubstring("maximum runtime exceeded")))
+	})
+
+	It("should not enforce the timeouts while the process is paused", func() {
+		Expect(executor.PauseProcess("sleeper")).To(Succeed())
+		Consistently(done, "500ms").ShouldNot(BeClosed())
+
+		Expect(executor.ResumeProcess("sleeper")).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(runErr).To(MatchError(ContainSubstring("maximum runtime exceeded")))
+	})
+
+	It("should stop a paused process without waiting for the interrupt timeout", func() {
+		Expect(executor.PauseProcess("sleeper")).To(Succeed())
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(runErr).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,276 @@
+package prox
+
+import (
+	"net/http"
+)
+
+// handleDashboard serves the web dashboard. It is a single page without any
+// external assets that uses the HTTP API to show all processes and to stream
+// their merged output.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeHTTPError(w, serverErrorf(CodeNotFound, "not found: %s", r.URL.Path))
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeHTTPError(w, serverErrorf(CodeBadRequest, "method %s is not allowed", r.Method))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write([]byte(dashboardHTML))
+}
+
+// dashboardHTML is the complete web dashboard. The colors of the process names
+// correspond to the colors of the colorPalette in the terminal.
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>prox</title>
+<style>
+  * { box-sizing: border-box; }
+  body { margin: 0; font: 14px/1.4 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; background: #1e1e1e; color: #d4d4d4; display: flex; flex-direction: column; height: 100vh; }
+  header { display: flex; align-items: center; gap: 1em; padding: .5em 1em; background: #252526; border-bottom: 1px solid #333; }
+  header h1 { font-size: 1.2em; margin: 0; }
+  #status { font-size: .85em; color: #888; }
+  #status.connected { color: #4e9a06; }
+  #status.disconnected { color: #cc0000; }
+  main { display: flex; flex: 1; min-height: 0; }
+  #processes { width: 32em; overflow: auto; border-right: 1px solid #333; }
+  table { width: 100%; border-collapse: collapse; }
+  th, td { text-align: left; padding: .3em .5em; border-bottom: 1px solid #2d2d2d; white-space: nowrap; }
+  th { color: #888; font-weight: normal; font-size: .85em; }
+  td.num { font-variant-numeric: tabular-nums; }
+  .state-running { color: #4e9a06; }
+  .state-scheduled { color: #c4a000; }
+  .state-paused { color: #3465a4; }
+  .state-stopped { color: #888; }
+  button { background: #3a3d41; color: #d4d4d4; border: 1px solid #555; border-radius: 3px; padding: .1em .6em; cursor: pointer; font-size: .85em; }
+  button:hover { background: #45494e; }
+  #logs { flex: 1; display: flex; flex-direction: column; min-width: 0; }
+  .toolbar { display: flex; align-items: center; gap: .8em; padding: .4em 1em; border-bottom: 1px solid #333; font-size: .85em; }
+  .toolbar select, .toolbar input[type=text] { background: #3a3d41; color: #d4d4d4; border: 1px solid #555; border-radius: 3px; padding: .1em .3em; }
+  #log { flex: 1; overflow: auto; padding: .5em 1em; font: 13px/1.35 Menlo, Consolas, "DejaVu Sans Mono", monospace; white-space: pre-wrap; word-break: break-all; }
+  .line .name { font-weight: bold; }
+  .line .sep { color: #666; }
+  .line.tag-error, .line.tag-fatal { color: #ef2929; font-weight: bold; }
+  .c-cyan { color: #06989a; }
+  .c-yellow { color: #c4a000; }
+  .c-green { color: #4e9a06; }
+  .c-magenta { color: #75507b; }
+  .c-red { color: #cc0000; }
+  .c-blue { color: #3465a4; }
+  .c- { color: #d4d4d4; }
+</style>
+</head>
+<body>
+<header>
+  <h1>prox</h1>
+  <span id="status">connecting…</span>
+</header>
+<main>
+  <section id="processes">
+    <table>
+      <thead><tr><th title="show output">log</th><th>process</th><th>state</th><th>pid</th><th>uptime</th><th></th></tr></thead>
+      <tbody id="process-list"></tbody>
+    </table>
+  </section>
+  <section id="logs">
+    <div class="toolbar">
+      <label>level <select id="level">
+        <option value="0">all</option>
+        <option value="1">debug+</option>
+        <option value="2">info+</option>
+        <option value="3">warn+</option>
+        <option value="4">error+</option>
+      </select></label>
+      <label>tag <select id="tag"><option value="">all</option></select></label>
+      <label>grep <input type="text" id="grep" placeholder="text"></label>
+      <label><input type="checkbox" id="follow" checked> follow</label>
+      <button id="clear">clear</button>
+    </div>
+    <div id="log"></div>
+  </section>
+</main>
+<script>
+(function() {
+  "use strict";
+
+  var maxLines = 5000;
+  var lines = [];
+  var hidden = {};  // processes whose output is hidden
+  var colors = {};  // process name to color name
+  var tags = {};    // all tags that have been seen so far
+  var levels = { trace: 1, debug: 1, info: 2, notice: 2, warn: 3, warning: 3, err: 4, error: 4, crit: 5, critical: 5, fatal: 5, panic: 5, dpanic: 5 };
+
+  var $ = function(id) { return document.getElementById(id); };
+  var el = function(tag, className, text) {
+    var e = document.createElement(tag);
+    if (className) e.className = className;
+    if (text !== undefined) e.textContent = text;
+    return e;
+  };
+
+  function formatUptime(seconds) {
+    seconds = Math.floor(seconds || 0);
+    var h = Math.floor(seconds / 3600), m = Math.floor(seconds % 3600 / 60), s = seconds % 60;
+    if (h > 0) return h + "h" + m + "m" + s + "s";
+    if (m > 0) return m + "m" + s + "s";
+    return s + "s";
+  }
+
+  function control(name, action) {
+    fetch("/v1/processes/" + encodeURIComponent(name) + "/" + action, { method: "POST" })
+      .then(function(resp) {
+        if (resp.ok) return;
+        return resp.json().then(function(body) { alert(body.message); });
+      })
+      .then(refresh);
+  }
+
+  function button(label, name, action) {
+    var b = el("button", "", label);
+    b.onclick = function() { control(name, action); };
+    return b;
+  }
+
+  function renderProcesses(processes) {
+    var tbody = $("process-list");
+    tbody.textContent = "";
+    processes.forEach(function(p) {
+      colors[p.name] = p.color || "";
+
+      var show = el("input");
+      show.type = "checkbox";
+      show.checked = !hidden[p.name];
+      show.onchange = function() {
+        if (show.checked) delete hidden[p.name]; else hidden[p.name] = true;
+        renderLog();
+      };
+
+      var actions = el("td");
+      actions.appendChild(button("restart", p.name, "restart"));
+      actions.appendChild(document.createTextNode(" "));
+      if (p.state === "running") {
+        actions.appendChild(button("stop", p.name, "stop"));
+        actions.appendChild(document.createTextNode(" "));
+        actions.appendChild(button("pause", p.name, "pause"));
+      } else if (p.state === "paused") {
+        actions.appendChild(button("stop", p.name, "stop"));
+        actions.appendChild(document.createTextNode(" "));
+        actions.appendChild(button("resume", p.name, "resume"));
+      } else {
+        actions.appendChild(button("start", p.name, "start"));
+      }
+
+      var tr = el("tr");
+      var td = el("td");
+      td.appendChild(show);
+      tr.appendChild(td);
+      tr.appendChild(el("td", "name c-" + colors[p.name], p.name));
+      tr.appendChild(el("td", "state-" + p.state, p.state));
+      tr.appendChild(el("td", "num", p.pid ? String(p.pid) : "-"));
+      tr.appendChild(el("td", "num", p.pid ? formatUptime(p.uptime_seconds) : "-"));
+      tr.appendChild(actions);
+      tbody.appendChild(tr);
+    });
+  }
+
+  function refresh() {
+    fetch("/v1/processes")
+      .then(function(resp) { return resp.json(); })
+      .then(renderProcesses)
+      .catch(function() {});
+  }
+
+  function matches(l) {
+    if (hidden[l.process]) return false;
+
+    var minLevel = Number($("level").value);
+    if (minLevel > 0 && (levels[(l.level || "").toLowerCase()] || 0) < minLevel) return false;
+
+    var tag = $("tag").value;
+    if (tag && (l.tags || []).indexOf(tag) < 0) return false;
+
+    var grep = $("grep").value;
+    if (grep && l.line.toLowerCase().indexOf(grep.toLowerCase()) < 0) return false;
+
+    return true;
+  }
+
+  function lineElement(l) {
+    var div = el("div", "line");
+    (l.tags || []).forEach(function(t) { div.classList.add("tag-" + t); });
+    div.title = new Date(l.time).toLocaleTimeString();
+    div.appendChild(el("span", "name c-" + (colors[l.process] || ""), l.process));
+    div.appendChild(el("span", "sep", " │ "));
+    div.appendChild(el("span", "msg", l.line));
+    return div;
+  }
+
+  function scroll() {
+    if ($("follow").checked) $("log").scrollTop = $("log").scrollHeight;
+  }
+
+  function renderLog() {
+    var log = $("log");
+    log.textContent = "";
+    lines.filter(matches).forEach(function(l) { log.appendChild(lineElement(l)); });
+    scroll();
+  }
+
+  function addTags(l) {
+    (l.tags || []).forEach(function(t) {
+      if (tags[t]) return;
+      tags[t] = true;
+      var option = el("option", "", t);
+      option.value = t;
+      $("tag").appendChild(option);
+    });
+  }
+
+  function addLine(l) {
+    lines.push(l);
+    addTags(l);
+    if (lines.length > maxLines) lines.shift();
+
+    if (!matches(l)) return;
+    var log = $("log");
+    log.appendChild(lineElement(l));
+    while (log.childNodes.length > maxLines) log.removeChild(log.firstChild);
+    scroll();
+  }
+
+  function connect() {
+    var events = new EventSource("/v1/logs?history=500");
+    events.onopen = function() {
+      $("status").textContent = "connected";
+      $("status").className = "connected";
+      refresh();
+    };
+    events.onerror = function() {
+      $("status").textContent = "disconnected, retrying…";
+      $("status").className = "disconnected";
+    };
+    events.addEventListener("output", function(e) {
+      addLine(JSON.parse(e.data));
+    });
+  }
+
+  ["level", "tag"].forEach(function(id) { $(id).onchange = renderLog; });
+  $("grep").oninput = renderLog;
+  $("follow").onchange = scroll;
+  $("clear").onclick = function() { lines = []; renderLog(); };
+
+  refresh();
+  setInterval(refresh, 2000);
+  connect();
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,200 @@
+package prox
+
+import (
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// An Event describes a change in the lifecycle of a single process or of the
+// whole stack that is managed by an Executor.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     EventType `json:"type"`
+	Process  string    `json:"process,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"` // only set for EventExited
+	Error    string    `json:"error,omitempty"`
+}
+
+// An EventType indicates what kind of change an Event describes.
+type EventType string
+
+// All event types that are emitted by the Executor.
+const (
+	EventStarted   EventType = "started"   // process was started for the first time
+	EventRestarted EventType = "restarted" // process was started again (e.g. after a reload)
+	EventReady     EventType = "ready"     // process has become ready (see Process.Ready)
+	EventExited    EventType = "exited"    // process finished on its own (successfully or with an error)
+	EventStopped   EventType = "stopped"   // process was interrupted by prox
+	EventShutdown  EventType = "shutdown"  // the stack is shutting down
+)
+
+// EventTypes returns all known event types.
+func EventTypes() []EventType {
+	return []EventType{EventStarted, EventRestarted, EventReady, EventExited, EventStopped, EventShutdown}
+}
+
+// An EventFilter selects events by process name and event type. Empty lists
+// match all processes or event types respectively.
+type EventFilter struct {
+	Processes []string
+	Types     []EventType
+}
+
+// Match returns true if the event passes the filter. Stack-wide events (i.e.
+// events without a process) are never filtered by process name.
+func (f EventFilter) Match(e Event) bool {
+	if len(f.Types) > 0 && !containsEventType(f.Types, e.Type) {
+		return false
+	}
+
+	if len(f.Processes) > 0 && e.Process != "" && !containsString(f.Processes, e.Process) {
+		return false
+	}
+
+	return true
+}
+
+func containsEventType(types []EventType, t EventType) bool {
+	for _, x := range types {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// eventBufferSize is the amount of events that are buffered for a single
+// subscriber. If a subscriber is too slow to consume its events, all further
+// events are dropped for that subscriber until it has caught up again.
+const eventBufferSize = 100
+
+// An eventBus distributes events to all of its subscribers without ever
+// blocking the publisher.
+type eventBus struct {
+	mu      sync.Mutex
+	subs    map[chan Event]struct{}
+	started map[string]bool // all processes that have been started at least once
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		subs:    map[chan Event]struct{}{},
+		started: map[string]bool{},
+	}
+}
+
+// subscribe registers a new subscriber. The returned function must be called
+// to unsubscribe again once the caller is no longer interested in events.
+func (b *eventBus) subscribe() (events <-chan Event, unsubscribe func()) {
+	c := make(chan Event, eventBufferSize)
+
+	b.mu.Lock()
+	b.subs[c] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, c)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// subscriberCount returns the amount of currently registered subscribers.
+func (b *eventBus) subscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// publish sends the event to all subscribers. If the event has no time set,
+// the current time is used. The Executor calls the eventBus synchronously when
+// a lifecycle change happens so this is the time of the change itself.
+func (b *eventBus) publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for c := range b.subs {
+		select {
+		case c <- e:
+		default:
+			// subscriber is too slow, drop the event
+		}
+	}
+}
+
+// ProcessStarted implements the Observer interface.
+func (b *eventBus) ProcessStarted(name string) {
+	b.mu.Lock()
+	restarted := b.started[name]
+	b.started[name] = true
+	b.mu.Unlock()
+
+	if restarted {
+		b.publish(Event{Type: EventRestarted, Process: name})
+	} else {
+		b.publish(Event{Type: EventStarted, Process: name})
+	}
+}
+
+// ProcessReady implements the ReadinessObserver interface.
+func (b *eventBus) ProcessReady(name string) {
+	b.publish(Event{Type: EventReady, Process: name})
+}
+
+// ProcessExited implements the Observer interface.
+func (b *eventBus) ProcessExited(name string, status ExitStatus, err error) {
+	if status == StatusInterrupted {
+		b.publish(Event{Type: EventStopped, Process: name})
+		return
+	}
+
+	code := exitCode(err)
+	event := Event{Type: EventExited, Process: name, ExitCode: &code}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	b.publish(event)
+}
+
+// ProcessOutput implements the Observer interface. Output is not part of the
+// lifecycle events and thus it is ignored.
+func (*eventBus) ProcessOutput(string, string) {}
+
+// Shutdown implements the Observer interface.
+func (b *eventBus) Shutdown() {
+	b.publish(Event{Type: EventShutdown})
+}
+
+// exitCode returns the exit code of a process that finished with the given
+// error. If the error does not contain an exit code, -1 is returned.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if exitErr, ok := errors.Cause(err).(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"io"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -16,9 +20,31 @@ type Executor struct {
 	debug        bool
 	noColors     bool
 	proxLogColor color
-	running      map[string]process
-	outputs      map[string]*multiWriter
+	proxOutput   io.Writer // output of the prox logger (e.g. to print reload diffs)
 	messages     chan message
+	events       *eventBus
+	history      *outputHistory
+	logFiles     *logFiles // nil unless the output is persisted via SetLogDir(…)
+
+	observersMu sync.Mutex
+	observers   []*observerQueue
+
+	mu        sync.Mutex
+	ctx       context.Context // context of the current run
+	logger    *zap.Logger
+	out       *output                       // creates the outputs of new processes
+	configs   map[string]Process            // configuration of processes started via Run or Reload
+	running   map[string]process            // all processes that have not yet finished
+	outputs   map[string]*multiWriter       // the output of each process by name
+	colors    map[string]color              // the color of each process by name
+	parsers   map[string]*processJSONOutput // parses the structured output of each process by name
+	marks     map[string]*processMark       // the mark of each marked process by name
+	runs      map[string]*processRun        // the current run of each running process
+	stopped   map[process]bool              // processes that were stopped on purpose (e.g. to reload them)
+	signaled  map[process]time.Time         // processes that were sent a terminating signal via Signal(…)
+	restarts  map[string]process            // processes to start once their previous instance has finished
+	schedules map[string]*scheduledProcess  // processes that are started periodically
+	done      bool                          // set once all processes have finished and no new ones can be started
 }
 
 // messages are passed to signal that a specific process has finished along with
@@ -26,30 +52,96 @@ type Executor struct {
 // a single message to eventually be sent to the Executor.
 type message struct {
 	p      process
-	status status
+	status ExitStatus
 	err    error
 }
 
-// A status indicates why a process has finished.
-type status int
-
-const (
-	statusSuccess     status = iota // process finished with error code 0
-	statusError                     // process failed with some error
-	statusInterrupted               // process was cancelled because the context interrupted
-)
-
 // NewExecutor creates a new Executor. The debug flag controls whether debug
 // logging should be activated. If debug is false then only warnings and errors
 // will be logged.
 func NewExecutor(debug bool) *Executor {
-	return &Executor{
+	e := &Executor{
 		output:       os.Stdout,
 		debug:        debug,
 		proxLogColor: colorWhite,
+		messages:     make(chan message),
+		events:       newEventBus(),
+		history:      newOutputHistory(),
+		configs:      map[string]Process{},
 		running:      map[string]process{},
 		outputs:      map[string]*multiWriter{},
-		messages:     make(chan message),
+		colors:       map[string]color{},
+		parsers:      map[string]*processJSONOutput{},
+		marks:        map[string]*processMark{},
+		runs:         map[string]*processRun{},
+		stopped:      map[process]bool{},
+		signaled:     map[process]time.Time{},
+		restarts:     map[string]process{},
+		schedules:    map[string]*scheduledProcess{},
+	}
+
+	e.history.annotate = e.annotateLine
+	e.addObserver(e.history, -1) // the history must not miss any output
+	return e
+}
+
+// AddObserver registers an Observer that is notified about the lifecycle and
+// output of all processes. Observers must be added before the Executor is
+// started via Executor.Run(…).
+func (e *Executor) AddObserver(o Observer) {
+	e.addObserver(o, maxPendingNotifications)
+}
+
+// addObserver registers an Observer whose lines of output are dropped if more
+// than limit notifications are pending. If limit is negative, no output is
+// dropped which is only viable for observers that never block.
+func (e *Executor) addObserver(o Observer, limit int) {
+	e.mu.Lock()
+	logger := e.logger
+	e.mu.Unlock()
+
+	q := newObserverQueue(o, logger)
+	q.limit = limit
+
+	e.observersMu.Lock()
+	e.observers = append(e.observers, q)
+	e.observersMu.Unlock()
+}
+
+// notify passes a lifecycle notification to all registered observers. The
+// event bus is notified synchronously so the events are stamped with the time
+// of the actual lifecycle change instead of the time they were delivered.
+func (e *Executor) notify(f func(Observer)) {
+	f(e.events)
+	e.push(f, false)
+}
+
+// notifyOutput passes a line of output to all registered observers. It is
+// dropped for observers that have fallen behind.
+func (e *Executor) notifyOutput(f func(Observer)) {
+	e.push(f, true)
+}
+
+func (e *Executor) push(f func(Observer), lossy bool) {
+	e.observersMu.Lock()
+	for _, q := range e.observers {
+		q.push(f, lossy)
+	}
+	e.observersMu.Unlock()
+}
+
+// closeObservers blocks until all pending notifications have been delivered to
+// the observers.
+func (e *Executor) closeObservers() {
+	e.observersMu.Lock()
+	defer e.observersMu.Unlock()
+
+	for _, q := range e.observers {
+		q.close()
+	}
+
+	if e.logFiles != nil {
+		e.logFiles.close()
 	}
 }
 
@@ -59,9 +151,32 @@ func (e *Executor) DisableColoredOutput() {
 	e.proxLogColor = colorNone
 }
 
+// SetHistoryLimit sets the maximum number of lines and bytes of output that are
+// kept per process so clients can see the recent output (e.g. via prox tail).
+// If either limit is exceeded the oldest lines are dropped. The limits must be
+// set before the Executor is started via Executor.Run(…).
+func (e *Executor) SetHistoryLimit(lines, bytes int) {
+	e.history.setLimit(lines, bytes)
+}
+
+// SetLogDir persists the raw and the formatted output of each process in the
+// given directory. A log file that would exceed maxSize bytes is rotated and at
+// most maxFiles rotated files are kept per process, so the output of previous
+// runs is kept as well. The persisted output can be read via ReadLogs(…).
+// SetLogDir must be called before the Executor is started via Executor.Run(…).
+func (e *Executor) SetLogDir(dir string, maxSize int64, maxFiles int) {
+	e.logFiles = newLogFiles(dir, maxSize, maxFiles)
+}
+
 // Run starts all processes and blocks until all processes have finished or the
 // context is done (e.g. canceled). If a process crashes or the context is
 // canceled early, all running processes receive an interrupt signal.
+// Processes that have a custom Runner are executed via that Runner instead of
+// being started as shell process.
+//
+// Processes with a Schedule are not started immediately but each time they are
+// due. As long as there are scheduled processes, Run does not return unless
+// the context is done or another process has failed.
 func (e *Executor) Run(ctx context.Context, processes []Process) error {
 	logger := e.proxLogger(processes)
 
@@ -69,24 +184,44 @@ func (e *Executor) Run(ctx context.Context, processes []Process) error {
 	defer logger.Sync()
 	go e.monitorContext(ctx, logger)
 
-	output := e.newOutput(processes)
-	pp := make([]process, len(processes))
-	for i, p := range processes {
-		po := output.next(p)
-		e.outputs[p.Name] = po
-		log := logger.With(zap.String("process", p.Name))
-		pp[i] = newSystemProcess(p.Name, p.Script, p.Env, po, log)
+	e.mu.Lock()
+	e.out = e.newOutput(processes)
+	var pp []process
+	for _, p := range processes {
+		e.configs[p.Name] = p
+		if p.Schedule == "" {
+			pp = append(pp, e.newProcess(p, logger))
+			continue
+		}
+
+		sp, err := newScheduledProcess(p)
+		if err != nil {
+			e.mu.Unlock()
+			return err
+		}
+
+		e.processOutput(e.out, p)
+		e.schedules[p.Name] = sp
 	}
+	e.mu.Unlock()
 
 	return e.run(ctx, pp, logger)
 }
 
+// newProcess creates a new process from its configuration. The caller must
+// hold e.mu.
+func (e *Executor) newProcess(p Process, logger *zap.Logger) process {
+	po := e.processOutput(e.out, p)
+	log := logger.With(zap.String("process", p.Name))
+	return newProcess(p, po, log)
+}
+
 // proxOutput creates a logger for the prox Executor itself. The given processes
 // are required to calculate the prefix of the log output line.
 func (e *Executor) proxLogger(processes []Process) *zap.Logger {
 	output := e.newOutput(processes)
-	out := output.nextColored(Process{Name: "prox"}, e.proxLogColor)
-	return NewLogger(out, e.debug)
+	e.proxOutput = output.nextColored(Process{Name: "prox"}, e.proxLogColor)
+	return NewLogger(e.proxOutput, e.debug)
 }
 
 // newOutput creates a new *output based on the settings of e.
@@ -94,6 +229,75 @@ func (e *Executor) newOutput(processes []Process) *output {
 	return newOutput(processes, e.noColors, e.output)
 }
 
+// processOutput creates the output of a single process and makes it available
+// for tailing and observers. If the process had an output already (e.g. because
+// it is restarted) the existing output is reused so clients that are currently
+// tailing the process keep receiving its output. The caller must hold e.mu.
+func (e *Executor) processOutput(output *output, p Process) *multiWriter {
+	if po, ok := e.outputs[p.Name]; ok {
+		return po
+	}
+
+	c := output.colors.next()
+	po := newMultiWriter(output.formatted(p, c, e.marks[p.Name]))
+	e.colors[p.Name] = c
+	po.AddWriter(newBufferedProcessOutput(observedOutput{name: p.Name, executor: e}))
+	e.outputs[p.Name] = po
+
+	if e.logFiles != nil {
+		e.logFiles.add(p)
+		po.AddWriter(newBufferedProcessOutput(logFileOutput{name: p.Name, files: e.logFiles}))
+	}
+
+	return po
+}
+
+// annotateLine adds the color of the process and the decoded message to a line
+// of output if the process emits structured log messages.
+func (e *Executor) annotateLine(l *OutputLine) {
+	l.Color = e.colorName(l.Process)
+	if !strings.HasPrefix(l.Line, "{") {
+		return
+	}
+
+	e.mu.Lock()
+	parser, ok := e.parsers[l.Process]
+	if !ok {
+		p, configured := e.configs[l.Process]
+		if !configured {
+			e.mu.Unlock()
+			return
+		}
+
+		if p.Output.Format == "" {
+			p.Output = DefaultStructuredOutput(p.Env)
+		}
+
+		parser = newProcessJSONOutput(nil, p.Output)
+		e.parsers[l.Process] = parser
+	}
+	e.mu.Unlock()
+
+	m, err := parser.decode([]byte(l.Line))
+	if err != nil {
+		return
+	}
+
+	l.Message = m.message
+	l.Level = m.level
+	l.Fields = m.fields
+	l.Tags = m.tags
+	l.TagColor = colorSpec(m.color)
+}
+
+// colorName returns the name of the color of a process (e.g. "cyan") or an
+// empty string if the output is not colored.
+func (e *Executor) colorName(name string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return colorName(e.colors[name])
+}
+
 // monitorContext simply logs an error message if the context is canceled.
 func (e *Executor) monitorContext(ctx context.Context, log *zap.Logger) {
 	<-ctx.Done()
@@ -104,65 +308,175 @@ func (e *Executor) monitorContext(ctx context.Context, log *zap.Logger) {
 
 func (e *Executor) run(ctx context.Context, processes []process, logger *zap.Logger) error {
 	ctx, cancel := context.WithCancel(ctx)
+	defer e.closeObservers()
+
+	// The stack is shutting down as soon as the context is done, regardless
+	// of whether it was canceled from the outside, by a failing process or
+	// because all processes have finished.
+	var once sync.Once
+	interruptAll := func() {
+		once.Do(func() {
+			e.notify(func(o Observer) { o.Shutdown() })
+			cancel()
+		})
+	}
+	defer interruptAll()
+
+	go func() {
+		<-ctx.Done()
+		interruptAll()
+	}()
+
 	e.startAll(ctx, processes, logger)
-	return e.waitForAll(cancel, logger)
+	return e.waitForAll(ctx, interruptAll, logger)
 }
 
 // StartAll starts all processes in a separate goroutine and then returns
-// immediately.
+// immediately. Scheduled processes are started once they are due.
 func (e *Executor) startAll(ctx context.Context, pp []process, logger *zap.Logger) {
 	logger.Info("Starting processes", zap.Int("amount", len(pp)))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.ctx = ctx
+	e.logger = logger
+	e.observersMu.Lock()
+	for _, q := range e.observers {
+		q.setLogger(logger)
+	}
+	e.observersMu.Unlock()
+
+	if e.logFiles != nil {
+		e.logFiles.setLogger(logger)
+	}
+	e.done = false
 	for _, p := range pp {
-		name := p.Name()
-		e.running[name] = p
+		e.start(p)
+	}
 
-		go func(p process) {
-			logger.Info("Starting process", zap.String("process_name", name))
-			e.runProcess(ctx, p)
-		}(p)
+	for name, sp := range e.schedules {
+		e.scheduleNext(name, sp)
 	}
 }
 
-// runProcess starts a single process and blocks until it has completed or failed.
-func (e *Executor) runProcess(ctx context.Context, p process) {
-	var result status
-	err := p.Run(ctx)
+// start runs a single process in a new goroutine. The caller must hold e.mu.
+func (e *Executor) start(p process) {
+	name := p.Name()
+	conf := e.configs[name]
+	run := newProcessRun(e.ctx, conf)
+	e.running[name] = p
+	e.runs[name] = run
 
-	switch {
-	case err == context.Canceled:
-		result = statusInterrupted
-	case err != nil:
-		result = statusError
-	default:
-		result = statusSuccess
+	output := e.outputs[name]
+	ready := newBufferedProcessOutput(newReadyWriter(conf.Ready, func() {
+		e.ready(name, run)
+	}))
+
+	fields := []zap.Field{zap.String("process_name", name)}
+	if sp, ok := e.schedules[name]; ok {
+		fields = append(fields, zap.Int("run", sp.runs))
+	}
+
+	go func() {
+		e.logger.Info("Starting process", fields...)
+		e.notify(func(o Observer) { o.ProcessStarted(name) })
+
+		if output != nil {
+			output.AddWriter(ready)
+			defer output.RemoveWriter(ready)
+		}
+
+		e.runProcess(run, p)
+	}()
+}
+
+// ready is called when a process run has become ready.
+func (e *Executor) ready(name string, run *processRun) {
+	if !run.markReady() {
+		return
 	}
 
+	e.logger.Info("Process is ready", zap.String("process_name", name))
+	e.notify(func(o Observer) {
+		if ro, ok := o.(ReadinessObserver); ok {
+			ro.ProcessReady(name)
+		}
+	})
+}
+
+// stop interrupts a single running process without treating its termination
+// as error. The caller must hold e.mu.
+func (e *Executor) stop(name string) {
+	p, ok := e.running[name]
+	if !ok {
+		return
+	}
+
+	e.stopped[p] = true
+	e.runs[name].cancel()
+}
+
+// runProcess starts a single process and blocks until it has completed or failed.
+func (e *Executor) runProcess(run *processRun, p process) {
+	err := p.Run(run.ctx)
+	result, err := run.finish(err)
 	e.messages <- message{p: p, status: result, err: err}
 }
 
-func (e *Executor) waitForAll(interruptAll func(), logger *zap.Logger) error {
+func (e *Executor) waitForAll(ctx context.Context, interruptAll func(), logger *zap.Logger) error {
 	var firstErr error
 	var firstErrProcess string
-	for len(e.running) > 0 {
-		logger.Debug("Waiting for processes to complete", zap.Int("amount", len(e.running)))
 
-		message := <-e.messages
+	// If only scheduled processes are left we must also wake up when the
+	// context is done since there might not be any more messages.
+	done := ctx.Done()
+	for e.waiting(logger) {
+		var message message
+		select {
+		case message = <-e.messages:
+		case <-done:
+			done = nil
+			continue
+		}
+
 		name := message.p.Name()
-		delete(e.running, name)
+		stopped, restart, scheduled := e.finished(message.p)
+		e.notify(func(o Observer) { o.ProcessExited(name, message.status, message.err) })
 
-		switch message.status {
-		case statusSuccess:
+		switch {
+		case message.status == StatusSuccess:
 			logger.Info("Process finished successfully", zap.String("process_name", name))
-		case statusInterrupted:
+		case message.status == StatusInterrupted:
 			logger.Info("Process was interrupted", zap.String("process_name", name))
-		case statusError:
-			logger.Error("Process error", zap.String("process_name", name), zap.Error(message.err))
+		case stopped:
+			logger.Info("Process was stopped", zap.String("process_name", name), zap.Error(message.err))
+		default:
+			msg := "Process error"
+			if isTimeout(message.err) {
+				msg = "Process timed out"
+			}
+
+			logger.Error(msg, zap.String("process_name", name), zap.Error(message.err))
+			if scheduled {
+				// like a cron job, a failing run does not stop the other processes
+				break
+			}
+
 			if firstErr == nil {
 				firstErr = message.err
 				firstErrProcess = name
 			}
 			interruptAll()
 		}
+
+		if restart != nil {
+			e.mu.Lock()
+			if e.ctx.Err() == nil {
+				e.start(restart)
+			}
+			e.mu.Unlock()
+		}
 	}
 
 	if firstErr != nil {
@@ -175,15 +489,140 @@ func (e *Executor) waitForAll(interruptAll func(), logger *zap.Logger) error {
 	return errors.Wrap(firstErr, "first error")
 }
 
+// waiting returns true as long as there are running processes or processes
+// that are scheduled to run in the future. Once it has returned false, no new
+// processes can be started anymore.
+func (e *Executor) waiting(logger *zap.Logger) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.running) == 0 && (len(e.schedules) == 0 || e.ctx.Err() != nil) {
+		e.done = true
+		e.stopSchedules()
+		return false
+	}
+
+	logger.Debug("Waiting for processes to complete", zap.Int("amount", len(e.running)))
+	return true
+}
+
+// finished removes a process that has finished from the running processes.
+// It returns whether the process was stopped on purpose, if there is a new
+// instance of the process that should be started now (e.g. a queued run of a
+// scheduled process) and whether the process is scheduled.
+func (e *Executor) finished(p process) (stopped bool, restart process, scheduled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	name := p.Name()
+	if e.running[name] == p {
+		delete(e.running, name)
+		delete(e.runs, name)
+	}
+
+	stopped = e.stopped[p]
+	delete(e.stopped, p)
+
+	if t, ok := e.signaled[p]; ok {
+		stopped = stopped || time.Since(t) < signalGracePeriod
+		delete(e.signaled, p)
+	}
+
+	restart = e.restarts[name]
+	delete(e.restarts, name)
+
+	sp, scheduled := e.schedules[name]
+	if scheduled && sp.queued && restart == nil && e.ctx.Err() == nil {
+		sp.queued = false
+		restart = e.scheduledRun(name, sp)
+	}
+
+	return stopped, restart, scheduled
+}
+
 // Info returns information about a running process. If there is no such process
-// running process a ProcessInfo with a PID of -1 is returned.
+// running process a ProcessInfo with a PID of -1 is returned. For scheduled
+// processes the info also contains the time of the next run and how often the
+// process was started already.
 func (e *Executor) Info(processName string) ProcessInfo {
+	e.mu.Lock()
 	p, ok := e.running[processName]
-	if !ok {
-		return ProcessInfo{PID: -1}
+	paused := ok && e.runs[processName] != nil && e.runs[processName].isPaused()
+	_, configured := e.configs[processName]
+	var next time.Time
+	var runs int
+	sp, scheduled := e.schedules[processName]
+	if scheduled {
+		next, runs = sp.next, sp.runs
+	}
+	e.mu.Unlock()
+
+	inf := ProcessInfo{PID: -1}
+	if ok {
+		inf = p.Info()
+	}
+
+	switch {
+	case paused:
+		inf.State = StatePaused
+	case ok:
+		inf.State = StateRunning
+	case scheduled:
+		inf.State = StateScheduled
+	case configured:
+		inf.State = StateStopped
 	}
 
-	inf := p.Info()
 	inf.Name = processName
+	inf.NextRun = next
+	inf.Runs = runs
 	return inf
 }
+
+// runningProcesses returns the sorted names of all running processes.
+func (e *Executor) runningProcesses() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.running))
+	for name := range e.running {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// listedProcesses returns the sorted names of all processes that are either
+// running or scheduled.
+func (e *Executor) listedProcesses() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.running)+len(e.schedules))
+	for name := range e.running {
+		names = append(names, name)
+	}
+	for name := range e.schedules {
+		if _, ok := e.running[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// outputNames returns the sorted names of all processes with output.
+func (e *Executor) outputNames() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.outputs))
+	for name := range e.outputs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -1,8 +1,14 @@
 package prox
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("Executor", func() {
@@ -30,6 +36,119 @@ var _ = Describe("Executor", func() {
 		Eventually(executor.IsDone).Should(BeTrue(), "it should return once all processes are done")
 	})
 
+	It("should publish lifecycle events", func() {
+		events, unsubscribe := executor.events.subscribe()
+		defer unsubscribe()
+
+		p1 := &TestProcess{name: "p1"}
+		p2 := &TestProcess{name: "p2"}
+
+		go executor.Run(p1, p2)
+		EventuallyAllProcessesShouldHaveStarted(p1, p2)
+
+		var started []string
+		for i := 0; i < 2; i++ {
+			var e Event
+			Eventually(events).Should(Receive(&e))
+			Expect(e.Type).To(Equal(EventStarted))
+			started = append(started, e.Process)
+		}
+		Expect(started).To(ConsistOf("p1", "p2"))
+
+		p1.Fail()
+
+		var e Event
+		Eventually(events).Should(Receive(&e))
+		Expect(e.Type).To(Equal(EventExited))
+		Expect(e.Process).To(Equal("p1"))
+		Expect(e.Error).To(Equal("TestProcess simulated a failure"))
+		Expect(e.ExitCode).NotTo(BeNil())
+		Expect(*e.ExitCode).To(Equal(-1))
+
+		Eventually(events).Should(Receive(&e))
+		Expect(e.Type).To(Equal(EventShutdown))
+
+		Eventually(events).Should(Receive(&e))
+		Expect(e.Type).To(Equal(EventStopped))
+		Expect(e.Process).To(Equal("p2"))
+	})
+
+	It("should stamp events with the time of the lifecycle change even if an observer is slow", func() {
+		slow := &recordingObserver{block: make(chan bool)}
+		executor.AddObserver(slow)
+
+		events, unsubscribe := executor.events.subscribe()
+		defer unsubscribe()
+
+		p1 := &TestProcess{name: "p1"}
+		go executor.Run(p1)
+		Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+		var e Event
+		Eventually(events).Should(Receive(&e), "events should not wait for other observers")
+		Expect(e.Type).To(Equal(EventStarted))
+		Expect(e.Time).To(BeTemporally("~", time.Now(), time.Second))
+
+		time.Sleep(100 * time.Millisecond)
+		close(slow.block)
+		p1.Finish()
+
+		Eventually(events).Should(Receive(&e))
+		Expect(e.Type).To(Equal(EventExited))
+		Eventually(executor.IsDone).Should(BeTrue())
+	})
+
+	It("should run processes with a custom Runner", func() {
+		output := NewBuffer()
+		executor := TestNewExecutor(output)
+		executor.DisableColoredOutput()
+
+		block := make(chan bool)
+		processes := []Process{
+			{
+				Name: "custom",
+				Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+					fmt.Fprintln(w, "Hello from a Go function")
+					<-block
+					return nil
+				}),
+			},
+		}
+
+		done := make(chan error)
+		go func() {
+			done <- executor.Executor.Run(context.Background(), processes)
+		}()
+
+		Eventually(output).Should(Say(`custom   │ Hello from a Go function`))
+		Expect(executor.Info("custom").PID).To(BeNumerically(">", 0))
+
+		close(block)
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should interrupt custom Runners when the context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		processes := []Process{
+			{
+				Name: "custom",
+				Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}),
+			},
+		}
+
+		done := make(chan error)
+		go func() {
+			done <- executor.Executor.Run(ctx, processes)
+		}()
+
+		Consistently(done).ShouldNot(Receive())
+		cancel()
+		Eventually(done).Should(Receive(BeNil()), "an interrupted Runner is no error")
+	})
+
 	Context("when a process fails", func() {
 		It("should interrupt all other processes", func() {
 			p1 := &TestProcess{name: "p1"}
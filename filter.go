@@ -0,0 +1,201 @@
+package prox
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// levelSeverity maps the names of log levels to their severity.
+var levelSeverity = map[string]int{
+	"trace":    1,
+	"debug":    1,
+	"info":     2,
+	"notice":   2,
+	"warn":     3,
+	"warning":  3,
+	"err":      4,
+	"error":    4,
+	"crit":     5,
+	"critical": 5,
+	"fatal":    5,
+	"panic":    5,
+	"dpanic":   5,
+}
+
+// plainLevel finds the first level name in a line of unstructured output.
+var plainLevel = regexp.MustCompile(`(?i)\b(trace|debug|info|notice|warn|warning|err|error|crit|critical|fatal|panic|dpanic)\b`)
+
+// plainField finds key=value pairs in a line of unstructured output (e.g.
+// `user_id=42` or `msg="hello world"`).
+var plainField = regexp.MustCompile(`([\w.-]+)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+// conditionRegex matches the values of conditions that are regular expressions.
+var conditionRegex = regexp.MustCompile(`^/.+/[a-z]*$`)
+
+// A lineFilter selects the lines of output that are sent to a client.
+type lineFilter struct {
+	grep     *regexp.Regexp // matches the raw line
+	severity int            // the minimum severity of the level of a line
+	where    []fieldCondition
+}
+
+// A fieldCondition matches a single field of a line of output.
+type fieldCondition struct {
+	key   string
+	value string
+	re    *regexp.Regexp // set if the value is a regular expression
+}
+
+// newLineFilter creates a lineFilter from a regular expression (grep), the
+// minimum level and conditions in the form "key=value". The value may also be
+// a regular expression like "/foo|bar/i" (see TaggingRule). Empty arguments
+// are ignored.
+func newLineFilter(grep, level string, where []string) (*lineFilter, error) {
+	f := new(lineFilter)
+
+	if grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid grep expression")
+		}
+		f.grep = re
+	}
+
+	if level != "" {
+		severity, ok := levelSeverity[strings.ToLower(level)]
+		if !ok {
+			return nil, errors.Errorf("unknown level %q", level)
+		}
+		f.severity = severity
+	}
+
+	for _, w := range where {
+		i := strings.Index(w, "=")
+		if i <= 0 {
+			return nil, errors.Errorf(`invalid condition %q: expected "key=value"`, w)
+		}
+
+		c := fieldCondition{key: w[:i], value: w[i+1:]}
+		if conditionRegex.MatchString(c.value) {
+			c.re = parseValueRegex(c.value)
+			if c.re == nil {
+				return nil, errors.Errorf("invalid condition %q: invalid regular expression", w)
+			}
+		}
+
+		f.where = append(f.where, c)
+	}
+
+	return f, nil
+}
+
+// match returns true if the line passes all conditions of the filter. The
+// level and fields of structured log messages are taken from the parsed JSON
+// message while they are searched in the raw line of unstructured output.
+func (f *lineFilter) match(l OutputLine) bool {
+	if f.grep != nil && !f.grep.MatchString(l.Line) {
+		return false
+	}
+
+	if f.severity == 0 && len(f.where) == 0 {
+		return true
+	}
+
+	fields, structured := jsonFields(l.Line)
+
+	if f.severity > 0 {
+		level := l.Level
+		if !structured {
+			level = plainLevel.FindString(l.Line)
+		}
+
+		if levelSeverity[strings.ToLower(level)] < f.severity {
+			return false
+		}
+	}
+
+	if len(f.where) == 0 {
+		return true
+	}
+
+	if !structured {
+		fields = plainFields(l.Line)
+	}
+
+	for _, c := range f.where {
+		v, ok := lookupField(fields, c.key)
+		if !ok || !c.match(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c fieldCondition) match(value string) bool {
+	if c.re != nil {
+		return c.re.MatchString(value)
+	}
+
+	return value == c.value
+}
+
+// jsonFields decodes a line of output if it is a JSON object.
+func jsonFields(line string) (map[string]interface{}, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return nil, false
+	}
+
+	var fields map[string]interface{}
+	err := json.Unmarshal([]byte(line), &fields)
+	return fields, err == nil
+}
+
+// plainFields returns all key=value pairs of a line of unstructured output.
+func plainFields(line string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for _, m := range plainField.FindAllStringSubmatch(line, -1) {
+		value := m[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[m[1]] = value
+	}
+
+	return fields
+}
+
+// lookupField returns the value of a field as string. Nested fields of JSON
+// objects can be accessed by separating the keys with dots (e.g. "req.id").
+func lookupField(fields map[string]interface{}, key string) (string, bool) {
+	v, ok := fields[key]
+	if !ok {
+		i := strings.Index(key, ".")
+		if i < 0 {
+			return "", false
+		}
+
+		nested, isMap := fields[key[:i]].(map[string]interface{})
+		if !isMap {
+			return "", false
+		}
+
+		return lookupField(nested, key[i+1:])
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case nil:
+		return "null", true
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+}
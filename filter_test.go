@@ -0,0 +1,59 @@
+package prox
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("lineFilter", func() {
+	DescribeTable("matching lines",
+		func(grep, level string, where []string, line OutputLine, expected bool) {
+			f, err := newLineFilter(grep, level, where)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.match(line)).To(Equal(expected))
+		},
+
+		Entry("no filter", "", "", nil, OutputLine{Line: "foo"}, true),
+		Entry("grep match", "time(out)?", "", nil, OutputLine{Line: "request timeout"}, true),
+		Entry("grep mismatch", "timeout", "", nil, OutputLine{Line: "request done"}, false),
+		Entry("grep on JSON", "timeout", "", nil, OutputLine{Line: `{"msg":"timeout"}`}, true),
+
+		Entry("JSON level above", "", "warn", nil, OutputLine{Line: `{"level":"error"}`, Level: "error"}, true),
+		Entry("JSON level equal", "", "WARN", nil, OutputLine{Line: `{"level":"warning"}`, Level: "warning"}, true),
+		Entry("JSON level below", "", "warn", nil, OutputLine{Line: `{"level":"info","msg":"error"}`, Level: "info"}, false),
+		Entry("JSON without level", "", "warn", nil, OutputLine{Line: `{"msg":"error"}`}, false),
+		Entry("plain level", "", "warn", nil, OutputLine{Line: "2018/12/09 ERROR: boom"}, true),
+		Entry("plain level below", "", "warn", nil, OutputLine{Line: "[info] everything is fine"}, false),
+		Entry("plain without level", "", "warn", nil, OutputLine{Line: "hello"}, false),
+
+		Entry("JSON string field", "", "", []string{"user=alice"}, OutputLine{Line: `{"user":"alice"}`}, true),
+		Entry("JSON number field", "", "", []string{"user_id=42"}, OutputLine{Line: `{"user_id":42}`}, true),
+		Entry("JSON nested field", "", "", []string{"req.id=abc"}, OutputLine{Line: `{"req":{"id":"abc"}}`}, true),
+		Entry("JSON field mismatch", "", "", []string{"user_id=42"}, OutputLine{Line: `{"user_id":43}`}, false),
+		Entry("JSON missing field", "", "", []string{"user_id=42"}, OutputLine{Line: `{"msg":"user_id=42"}`}, false),
+		Entry("JSON regex field", "", "", []string{"path=/^/api/i"}, OutputLine{Line: `{"path":"/API/users"}`}, true),
+		Entry("all conditions", "", "", []string{"a=1", "b=2"}, OutputLine{Line: `{"a":1,"b":3}`}, false),
+		Entry("plain field", "", "", []string{"user_id=42"}, OutputLine{Line: "GET /users user_id=42 status=200"}, true),
+		Entry("plain quoted field", "", "", []string{"msg=hello world"}, OutputLine{Line: `level=info msg="hello world"`}, true),
+		Entry("plain field mismatch", "", "", []string{"user_id=42"}, OutputLine{Line: "user_id=420"}, false),
+		Entry("plain path is no regex", "", "", []string{"path=/api/v1"}, OutputLine{Line: "path=/api/v1"}, true),
+
+		Entry("combined", "timeout", "warn", []string{"user_id=42"},
+			OutputLine{Line: `{"level":"error","msg":"timeout","user_id":42}`, Level: "error"}, true,
+		),
+	)
+
+	DescribeTable("invalid filters",
+		func(grep, level string, where []string, expectedErr string) {
+			_, err := newLineFilter(grep, level, where)
+			Expect(err).To(MatchError(expectedErr))
+		},
+
+		Entry("grep", "(", "", nil, "invalid grep expression: error parsing regexp: missing closing ): `(`"),
+		Entry("level", "", "loud", nil, `unknown level "loud"`),
+		Entry("condition", "", "", []string{"user"}, `invalid condition "user": expected "key=value"`),
+		Entry("condition without key", "", "", []string{"=42"}, `invalid condition "=42": expected "key=value"`),
+		Entry("condition regex", "", "", []string{"a=/(/"}, `invalid condition "a=/(/": invalid regular expression`),
+	)
+})
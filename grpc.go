@@ -0,0 +1,472 @@
+package prox
+
+import (
+	"context"
+
+	"github.com/fgrosse/prox/proxpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// serveGRPC starts the gRPC server of the prox service (see proxpb/prox.proto)
+// on the gRPC connections of the unix socket. All streams are closed when the
+// context is done.
+func (s *Server) serveGRPC(ctx context.Context) {
+	s.grpcConns = newConnListener(s.listener.Addr())
+	s.grpc = grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+	)
+
+	service := &grpcService{server: s, ctx: ctx}
+	proxpb.RegisterProxServer(s.grpc, service)
+	if s.remoteListener != nil {
+		s.serveRemote(service)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := s.grpc.Serve(s.grpcConns)
+		if err != nil && err != grpc.ErrServerStopped && !isClosedConnectionError(err) {
+			s.logger.Error("Failed to serve gRPC", zap.Error(err))
+		}
+	}()
+}
+
+// unaryInterceptor checks the protocol version of the client, logs all
+// commands and converts errors into gRPC status errors that contain the
+// ErrorCode.
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	s.logger.Info("Received command from prox client", zap.String("method", info.FullMethod))
+	grpc.SetHeader(ctx, versionMetadata())
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if err := checkVersion(md); err != nil {
+		s.logger.Error("prox client uses an unsupported protocol version", zap.String("method", info.FullMethod), zap.Error(err))
+		return nil, grpcError(err)
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		s.logger.Error("prox client error", zap.String("method", info.FullMethod), zap.Error(err))
+		return nil, grpcError(err)
+	}
+
+	return resp, nil
+}
+
+// streamInterceptor is the equivalent of the unaryInterceptor for streams.
+func (s *Server) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	s.logger.Info("Received command from prox client", zap.String("method", info.FullMethod))
+	stream.SetHeader(versionMetadata())
+
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if err := checkVersion(md); err != nil {
+		s.logger.Error("prox client uses an unsupported protocol version", zap.String("method", info.FullMethod), zap.Error(err))
+		return grpcError(err)
+	}
+
+	err := handler(srv, stream)
+	if err != nil {
+		s.logger.Error("prox client error", zap.String("method", info.FullMethod), zap.Error(err))
+		return grpcError(err)
+	}
+
+	s.logger.Info("Prox client has closed the stream", zap.String("method", info.FullMethod))
+	return nil
+}
+
+// grpcService implements proxpb.ProxServer via the Executor of a Server.
+type grpcService struct {
+	proxpb.UnimplementedProxServer
+	server *Server
+	ctx    context.Context // done when the Server stops serving
+}
+
+func (g *grpcService) List(ctx context.Context, req *proxpb.ListRequest) (*proxpb.ListResponse, error) {
+	resp := new(proxpb.ListResponse)
+	for _, name := range g.server.Executor.listedProcesses() {
+		resp.Processes = append(resp.Processes, processToProto(g.server.Executor.Info(name)))
+	}
+
+	return resp, nil
+}
+
+func processToProto(inf ProcessInfo) *proxpb.Process {
+	p := &proxpb.Process{
+		Name:  inf.Name,
+		State: string(inf.State),
+		Runs:  int64(inf.Runs),
+	}
+
+	if inf.PID >= 0 {
+		p.Pid = int64(inf.PID)
+		p.Uptime = durationpb.New(inf.Uptime)
+	}
+
+	if !inf.NextRun.IsZero() {
+		p.NextRun = timestamppb.New(inf.NextRun)
+	}
+
+	return p
+}
+
+func processFromProto(p *proxpb.Process) ProcessInfo {
+	inf := ProcessInfo{
+		Name:  p.Name,
+		State: ProcessState(p.State),
+		PID:   -1,
+		Runs:  int(p.Runs),
+	}
+
+	if p.Pid > 0 {
+		inf.PID = int(p.Pid)
+		inf.Uptime = p.Uptime.AsDuration()
+	}
+
+	if p.NextRun != nil {
+		inf.NextRun = p.NextRun.AsTime().Local()
+	}
+
+	return inf
+}
+
+func (g *grpcService) Tail(req *proxpb.TailRequest, stream proxpb.Prox_TailServer) error {
+	if len(req.Processes) == 0 {
+		return serverErrorf(CodeBadRequest, "no processes to tail")
+	}
+
+	if g.ctx.Err() != nil {
+		// let clients that follow restarts try again
+		return status.Error(codes.Unavailable, "server is shutting down")
+	}
+
+	selector, err := newProcessSelector(req.Processes)
+	if err != nil {
+		return &ServerError{Code: CodeBadRequest, Message: err.Error()}
+	}
+
+	_, err = selector.resolve(g.server.Executor.outputNames())
+	if name, ok := err.(noSuchProcessError); ok {
+		return serverErrorf(CodeNotFound, "cannot tail unknown process %q", string(name))
+	} else if err != nil {
+		return err
+	}
+
+	filter, err := newLineFilter(req.Grep, req.Level, req.Where)
+	if err != nil {
+		return &ServerError{Code: CodeBadRequest, Message: err.Error()}
+	}
+
+	// sequence numbers of other instances (i.e. before prox was restarted)
+	// are meaningless
+	h := g.server.Executor.history
+	var since uint64
+	if req.Instance == h.instance {
+		since = req.SinceSeq
+	}
+
+	// processes that are added later (e.g. via reload) are followed as well if
+	// they match the patterns
+	history, lines, unsubscribe := h.subscribe(selector.match, since, -1)
+	defer unsubscribe()
+
+	history = filterLines(history, filter, int(req.History))
+
+	// the headers signal the client that we follow the output now
+	err = stream.SendHeader(metadata.Pairs(instanceMetadataKey, h.instance))
+	if err != nil {
+		return err
+	}
+
+	for _, l := range history {
+		err := stream.Send(outputLineToProto(l))
+		if err != nil {
+			return err
+		}
+	}
+
+	if req.NoFollow {
+		return nil
+	}
+
+	var dropped int // lines that were dropped before lines that did not match
+	for {
+		select {
+		case l := <-lines:
+			if !filter.match(l) {
+				dropped += l.Dropped
+				continue
+			}
+
+			l.Dropped += dropped
+			dropped = 0
+			err := stream.Send(outputLineToProto(l))
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-g.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// filterLines returns at most limit lines that match the filter (all lines if
+// limit is negative).
+func filterLines(lines []OutputLine, filter *lineFilter, limit int) []OutputLine {
+	var matching []OutputLine
+	for _, l := range lines {
+		if filter.match(l) {
+			matching = append(matching, l)
+		}
+	}
+
+	if limit >= 0 && len(matching) > limit {
+		matching = matching[len(matching)-limit:]
+	}
+
+	return matching
+}
+
+func outputLineToProto(l OutputLine) *proxpb.OutputLine {
+	pl := &proxpb.OutputLine{
+		Seq:      l.Seq,
+		Time:     timestamppb.New(l.Time),
+		Process:  l.Process,
+		Stream:   l.Stream,
+		Line:     l.Line,
+		Color:    l.Color,
+		Dropped:  int64(l.Dropped),
+		Message:  l.Message,
+		Level:    l.Level,
+		Tags:     l.Tags,
+		TagColor: l.TagColor,
+	}
+
+	if l.Fields != nil {
+		// the fields were decoded from JSON so they can always be converted
+		pl.Fields, _ = structpb.NewStruct(l.Fields)
+	}
+
+	return pl
+}
+
+func outputLineFromProto(pl *proxpb.OutputLine) OutputLine {
+	l := OutputLine{
+		Seq:      pl.Seq,
+		Time:     pl.Time.AsTime().Local(),
+		Process:  pl.Process,
+		Stream:   pl.Stream,
+		Line:     pl.Line,
+		Color:    pl.Color,
+		Dropped:  int(pl.Dropped),
+		Message:  pl.Message,
+		Level:    pl.Level,
+		Tags:     pl.Tags,
+		TagColor: pl.TagColor,
+	}
+
+	if pl.Fields != nil {
+		l.Fields = pl.Fields.AsMap()
+	}
+
+	return l
+}
+
+func (g *grpcService) Events(req *proxpb.EventsRequest, stream proxpb.Prox_EventsServer) error {
+	filter := EventFilter{Processes: req.Processes}
+	for _, t := range req.Types {
+		filter.Types = append(filter.Types, EventType(t))
+	}
+
+	events, unsubscribe := g.server.Executor.events.subscribe()
+	defer unsubscribe()
+
+	err := stream.SendHeader(metadata.MD{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case e := <-events:
+			if !filter.Match(e) {
+				continue
+			}
+
+			err := stream.Send(eventToProto(e))
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-g.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func eventToProto(e Event) *proxpb.Event {
+	pe := &proxpb.Event{
+		Time:    timestamppb.New(e.Time),
+		Type:    string(e.Type),
+		Process: e.Process,
+		Error:   e.Error,
+	}
+
+	if e.ExitCode != nil {
+		code := int32(*e.ExitCode)
+		pe.ExitCode = &code
+	}
+
+	return pe
+}
+
+func eventFromProto(pe *proxpb.Event) Event {
+	e := Event{
+		Time:    pe.Time.AsTime().Local(),
+		Type:    EventType(pe.Type),
+		Process: pe.Process,
+		Error:   pe.Error,
+	}
+
+	if pe.ExitCode != nil {
+		code := int(*pe.ExitCode)
+		e.ExitCode = &code
+	}
+
+	return e
+}
+
+func (g *grpcService) Start(ctx context.Context, req *proxpb.ProcessRequest) (*proxpb.ProcessResponse, error) {
+	names, err := g.control(req.Processes, g.server.Executor.StartProcess)
+	return &proxpb.ProcessResponse{Processes: names}, err
+}
+
+func (g *grpcService) Stop(ctx context.Context, req *proxpb.ProcessRequest) (*proxpb.ProcessResponse, error) {
+	names, err := g.control(req.Processes, g.server.Executor.StopProcess)
+	return &proxpb.ProcessResponse{Processes: names}, err
+}
+
+func (g *grpcService) Restart(ctx context.Context, req *proxpb.ProcessRequest) (*proxpb.ProcessResponse, error) {
+	names, err := g.control(req.Processes, g.server.Executor.RestartProcess)
+	return &proxpb.ProcessResponse{Processes: names}, err
+}
+
+func (g *grpcService) Pause(ctx context.Context, req *proxpb.ProcessRequest) (*proxpb.ProcessResponse, error) {
+	names, err := g.control(req.Processes, g.server.Executor.PauseProcess)
+	return &proxpb.ProcessResponse{Processes: names}, err
+}
+
+func (g *grpcService) Resume(ctx context.Context, req *proxpb.ProcessRequest) (*proxpb.ProcessResponse, error) {
+	names, err := g.control(req.Processes, g.server.Executor.ResumeProcess)
+	return &proxpb.ProcessResponse{Processes: names}, err
+}
+
+func (g *grpcService) Signal(ctx context.Context, req *proxpb.SignalRequest) (*proxpb.SignalResponse, error) {
+	sig, err := ParseSignal(req.Signal)
+	if err != nil {
+		return nil, &ServerError{Code: CodeBadRequest, Message: err.Error()}
+	}
+
+	names, err := g.control(req.Processes, func(name string) error {
+		return g.server.Executor.Signal(name, sig, req.Group)
+	})
+
+	return &proxpb.SignalResponse{Processes: names}, err
+}
+
+func (g *grpcService) Mark(ctx context.Context, req *proxpb.MarkRequest) (*proxpb.ProcessResponse, error) {
+	m := Mark{Style: MarkStyle(req.Style), Where: req.Where}
+	if _, err := newProcessMark(m); err != nil {
+		return nil, &ServerError{Code: CodeBadRequest, Message: err.Error()}
+	}
+
+	names, err := g.control(req.Processes, func(name string) error {
+		return g.server.Executor.Mark(name, m)
+	})
+
+	return &proxpb.ProcessResponse{Processes: names}, err
+}
+
+func (g *grpcService) Unmark(ctx context.Context, req *proxpb.ProcessRequest) (*proxpb.ProcessResponse, error) {
+	names, err := g.control(req.Processes, g.server.Executor.Unmark)
+	return &proxpb.ProcessResponse{Processes: names}, err
+}
+
+// control applies a command to all processes that are selected by the
+// patterns and returns the names of the processes on which it has succeeded.
+// All processes are tried even if the command fails for some of them.
+func (g *grpcService) control(patterns []string, command func(name string) error) ([]string, error) {
+	selector, err := newProcessSelector(patterns)
+	if err != nil {
+		return nil, &ServerError{Code: CodeBadRequest, Message: err.Error()}
+	}
+
+	names, err := selector.resolve(g.server.Executor.allProcesses())
+	if err != nil {
+		return nil, err
+	}
+
+	var succeeded []string
+	errs := newMultiError()
+	for _, name := range names {
+		err := command(name)
+		if err != nil {
+			errs.Errors = append(errs.Errors, err)
+			continue
+		}
+
+		succeeded = append(succeeded, name)
+	}
+
+	switch len(errs.Errors) {
+	case 0:
+		return succeeded, nil
+	case 1:
+		return succeeded, errs.Errors[0]
+	default:
+		return succeeded, errs
+	}
+}
+
+func (g *grpcService) Reload(ctx context.Context, req *proxpb.ReloadRequest) (*proxpb.ReloadResponse, error) {
+	diff, err := g.server.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &proxpb.ReloadResponse{Added: diff.Added, Removed: diff.Removed}
+	for _, c := range diff.Changed {
+		resp.Changed = append(resp.Changed, &proxpb.ProcessChange{Name: c.Name, Fields: c.Fields})
+	}
+
+	return resp, nil
+}
+
+// Shutdown stops all processes exactly as if prox was interrupted via Ctrl-C
+// and returns once all processes have finished.
+func (g *grpcService) Shutdown(ctx context.Context, req *proxpb.ShutdownRequest) (*proxpb.ShutdownResponse, error) {
+	if g.server.shutdown == nil {
+		return nil, serverErrorf(CodeNotSupported, "shutdown is not supported by this server")
+	}
+
+	g.server.logger.Info("Client requested shutdown")
+	g.server.shutdown()
+
+	select {
+	case <-g.ctx.Done():
+		return new(proxpb.ShutdownResponse), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
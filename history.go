@@ -0,0 +1,222 @@
+package prox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The default limits of the output history of each process.
+const (
+	DefaultHistoryLines = 1000
+	DefaultHistoryBytes = 1 << 20 // 1 MiB
+)
+
+// An OutputLine is a single line of output of a process.
+type OutputLine struct {
+	Seq     uint64    `json:"seq"` // increases monotonically over the output of all processes
+	Time    time.Time `json:"time"`
+	Process string    `json:"process"`
+	Stream  string    `json:"stream"`          // StreamStdout or StreamStderr
+	Line    string    `json:"line"`            // the raw line
+	Color   string    `json:"color,omitempty"` // the color of the process in the prox output (e.g. "cyan")
+
+	// Dropped is the number of lines that were not sent to the client right
+	// before this line because the client was too slow to receive them.
+	Dropped int `json:"dropped,omitempty"`
+
+	// The following fields are only set for structured log messages. Fields
+	// is nil for unstructured output.
+	Message  string                 `json:"message,omitempty"`
+	Level    string                 `json:"level,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`    // all fields except the message and level
+	Tags     []string               `json:"tags,omitempty"`      // see TaggingRule
+	TagColor string                 `json:"tag_color,omitempty"` // the color of tagged messages (e.g. "red-bold")
+}
+
+// Format returns the line like prox prints it but without colors. Structured
+// log messages are printed with their level, message and the remaining fields
+// while all other lines are returned unchanged.
+func (l OutputLine) Format() string {
+	if l.Fields == nil {
+		return l.Line
+	}
+
+	m := structuredMessage{message: l.Message, level: l.Level, fields: l.Fields}
+	msg, err := m.format()
+	if err != nil {
+		return l.Line
+	}
+
+	return msg
+}
+
+// outputHistory is an Observer that keeps the recent output of each process
+// and passes new lines to its subscribers.
+type outputHistory struct {
+	instance string // distinguishes the sequence numbers of different prox processes
+
+	mu    sync.Mutex
+	seq   uint64
+	lines map[string]*lineBuffer
+	subs  map[chan OutputLine]*subscription
+
+	maxLines int // maximum number of lines per process
+	maxBytes int // maximum size of all lines of a process
+
+	// annotate optionally adds the color and the decoded structured log
+	// message to a line.
+	annotate func(*OutputLine)
+}
+
+func newOutputHistory() *outputHistory {
+	return &outputHistory{
+		instance: newInstanceID(),
+		lines:    map[string]*lineBuffer{},
+		subs:     map[chan OutputLine]*subscription{},
+		maxLines: DefaultHistoryLines,
+		maxBytes: DefaultHistoryBytes,
+	}
+}
+
+// newInstanceID returns a random ID for a new outputHistory.
+func newInstanceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// subscribe returns the history of all processes that match the filter (at
+// most limit lines, all lines if limit is negative) followed by all new lines
+// via the returned channel. Only lines with a sequence number greater than
+// since are returned. The returned function must be called to unsubscribe.
+//
+// If a subscriber is too slow to receive the lines, new lines are dropped
+// for it so the output of the processes is never blocked. The number of
+// dropped lines is set as OutputLine.Dropped on the next line it receives.
+func (h *outputHistory) subscribe(match func(process string) bool, since uint64, limit int) ([]OutputLine, <-chan OutputLine, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if since > h.seq {
+		// the client has seen lines of a previous prox instance
+		since = 0
+	}
+
+	var history []OutputLine
+	for name, b := range h.lines {
+		if !match(name) {
+			continue
+		}
+		for _, l := range b.all() {
+			if l.Seq > since {
+				history = append(history, l)
+			}
+		}
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Seq < history[j].Seq
+	})
+
+	if limit >= 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+
+	c := make(chan OutputLine, 100)
+	h.subs[c] = &subscription{match: match}
+
+	return history, c, func() {
+		h.mu.Lock()
+		delete(h.subs, c)
+		h.mu.Unlock()
+	}
+}
+
+// ProcessOutput implements the Observer interface by recording the line.
+func (h *outputHistory) ProcessOutput(name, line string) {
+	h.processStreamOutput(name, StreamStdout, line)
+}
+
+// processStreamOutput implements the streamObserver interface by recording the
+// line along with its stream.
+func (h *outputHistory) processStreamOutput(name, stream, line string) {
+	l := OutputLine{Time: time.Now(), Process: name, Stream: stream, Line: line}
+	if h.annotate != nil {
+		h.annotate(&l)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	l.Seq = h.seq
+
+	b, ok := h.lines[name]
+	if !ok {
+		b = new(lineBuffer)
+		h.lines[name] = b
+	}
+	b.add(l, h.maxLines, h.maxBytes)
+
+	for c, sub := range h.subs {
+		if !sub.match(name) {
+			continue
+		}
+
+		sl := l
+		sl.Dropped = sub.dropped
+		select {
+		case c <- sl:
+			sub.dropped = 0
+		default:
+			// subscriber is too slow
+			sub.dropped++
+		}
+	}
+}
+
+// A subscription selects the lines that are sent to a subscriber and counts
+// the lines that were dropped because the subscriber was too slow.
+type subscription struct {
+	match   func(process string) bool
+	dropped int
+}
+
+// The other Observer callbacks are not needed to record the output.
+func (h *outputHistory) ProcessStarted(string)                   {}
+func (h *outputHistory) ProcessExited(string, ExitStatus, error) {}
+func (h *outputHistory) Shutdown()                               {}
+
+// setLimit changes the maximum number of lines and bytes that are kept per
+// process.
+func (h *outputHistory) setLimit(lines, bytes int) {
+	h.mu.Lock()
+	h.maxLines, h.maxBytes = lines, bytes
+	h.mu.Unlock()
+}
+
+// A lineBuffer keeps the most recent lines of a process. If a new line exceeds
+// the maximum number of lines or bytes, the oldest lines are dropped.
+type lineBuffer struct {
+	lines []OutputLine
+	size  int // number of bytes of all lines
+}
+
+func (b *lineBuffer) add(l OutputLine, maxLines, maxBytes int) {
+	b.lines = append(b.lines, l)
+	b.size += len(l.Line)
+
+	for len(b.lines) > 0 && (len(b.lines) > maxLines || b.size > maxBytes) {
+		b.size -= len(b.lines[0].Line)
+		b.lines[0] = OutputLine{} // allow the line to be garbage collected
+		b.lines = b.lines[1:]
+	}
+}
+
+// all returns all lines of the buffer in the order they were added.
+func (b *lineBuffer) all() []OutputLine {
+	return b.lines
+}
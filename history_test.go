@@ -0,0 +1,169 @@
+package prox
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("outputHistory", func() {
+	all := func(string) bool { return true }
+
+	lines := func(history []OutputLine) []string {
+		var ll []string
+		for _, l := range history {
+			ll = append(ll, l.Line)
+		}
+		return ll
+	}
+
+	It("should keep the most recent lines of each process", func() {
+		h := newOutputHistory()
+		h.setLimit(2, 1000)
+
+		for i := 1; i <= 3; i++ {
+			h.ProcessOutput("a", fmt.Sprint("a", i))
+			h.ProcessOutput("b", fmt.Sprint("b", i))
+		}
+
+		history, _, unsubscribe := h.subscribe(all, 0, -1)
+		unsubscribe()
+		Expect(lines(history)).To(Equal([]string{"a2", "b2", "a3", "b3"}))
+
+		history, _, unsubscribe = h.subscribe(func(name string) bool { return name == "b" }, 0, -1)
+		unsubscribe()
+		Expect(lines(history)).To(Equal([]string{"b2", "b3"}))
+
+		history, _, unsubscribe = h.subscribe(all, 0, 3)
+		unsubscribe()
+		Expect(lines(history)).To(Equal([]string{"b2", "a3", "b3"}))
+	})
+
+	It("should limit the size of the history of each process", func() {
+		h := newOutputHistory()
+		h.setLimit(100, 10)
+
+		h.ProcessOutput("a", "12345")
+		h.ProcessOutput("a", "678")
+		h.ProcessOutput("a", "90")
+		h.ProcessOutput("b", "0123456789")
+
+		history, _, unsubscribe := h.subscribe(all, 0, -1)
+		unsubscribe()
+		Expect(lines(history)).To(Equal([]string{"12345", "678", "90", "0123456789"}))
+
+		h.ProcessOutput("a", "1")
+		history, _, unsubscribe = h.subscribe(all, 0, -1)
+		unsubscribe()
+		Expect(lines(history)).To(Equal([]string{"678", "90", "0123456789", "1"}))
+
+		h.ProcessOutput("a", "a line that is too long")
+		history, _, unsubscribe = h.subscribe(func(name string) bool { return name == "a" }, 0, -1)
+		unsubscribe()
+		Expect(history).To(BeEmpty())
+	})
+
+	It("should not keep any history if the limit is zero", func() {
+		h := newOutputHistory()
+		h.setLimit(0, 0)
+		h.ProcessOutput("a", "test")
+
+		history, lines, unsubscribe := h.subscribe(all, 0, -1)
+		defer unsubscribe()
+		Expect(history).To(BeEmpty())
+
+		h.ProcessOutput("a", "new line")
+		var l OutputLine
+		Expect(lines).To(Receive(&l))
+		Expect(l.Line).To(Equal("new line"))
+	})
+
+	It("should report the lines that were dropped for a slow subscriber", func() {
+		h := newOutputHistory()
+		_, lines, unsubscribe := h.subscribe(all, 0, -1)
+		defer unsubscribe()
+
+		for i := 1; i <= cap(lines)+5; i++ {
+			h.ProcessOutput("a", fmt.Sprint("line ", i))
+		}
+
+		for i := 1; i <= cap(lines); i++ {
+			var l OutputLine
+			Expect(lines).To(Receive(&l))
+			Expect(l.Line).To(Equal(fmt.Sprint("line ", i)))
+			Expect(l.Dropped).To(BeZero())
+		}
+
+		h.ProcessOutput("a", "next line")
+		var l OutputLine
+		Expect(lines).To(Receive(&l))
+		Expect(l.Line).To(Equal("next line"))
+		Expect(l.Dropped).To(Equal(5))
+
+		h.ProcessOutput("a", "last line")
+		Expect(lines).To(Receive(&l))
+		Expect(l.Dropped).To(BeZero())
+	})
+
+	It("should record the stream and the structured log message of each line", func() {
+		e := NewExecutor(false)
+		e.output = GinkgoWriter
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- e.Run(ctx, []Process{{
+				Name:   "p1",
+				Script: `sh -c 'echo plain; echo "{\"level\":\"error\",\"msg\":\"boom\",\"id\":1}" >&2; sleep 10'`,
+			}})
+		}()
+
+		var history []OutputLine
+		Eventually(func() []OutputLine {
+			history = recentOutput(e)
+			return history
+		}, "5s").Should(HaveLen(2))
+
+		cancel()
+		Eventually(done, "5s").Should(Receive())
+
+		plain, structured := history[0], history[1]
+		if plain.Stream == StreamStderr {
+			plain, structured = structured, plain // the order of stdout and stderr is not guaranteed
+		}
+
+		Expect(plain.Stream).To(Equal(StreamStdout))
+		Expect(plain.Line).To(Equal("plain"))
+		Expect(plain.Color).To(Equal("cyan"))
+		Expect(plain.Fields).To(BeNil())
+
+		Expect(structured.Stream).To(Equal(StreamStderr))
+		Expect(structured.Message).To(Equal("boom"))
+		Expect(structured.Level).To(Equal("error"))
+		Expect(structured.Fields).To(Equal(map[string]interface{}{"id": 1.0}))
+		Expect(structured.Tags).To(Equal([]string{"error"}))
+		Expect(structured.TagColor).To(Equal("red-bold"))
+	})
+})
+
+var _ = Describe("OutputLine", func() {
+	It("should format structured log messages like prox prints them", func() {
+		l := OutputLine{
+			Line:    `{"level":"error","msg":"boom","id":1}`,
+			Message: "boom",
+			Level:   "error",
+			Fields:  map[string]interface{}{"id": 1.0},
+		}
+		Expect(l.Format()).To(Equal("[ERROR]\tboom\t{ \"id\": 1 }"))
+
+		l.Fields = map[string]interface{}{}
+		Expect(l.Format()).To(Equal("[ERROR]\tboom"))
+	})
+
+	It("should not change unstructured output", func() {
+		l := OutputLine{Line: "GET /users"}
+		Expect(l.Format()).To(Equal("GET /users"))
+	})
+})
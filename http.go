@@ -0,0 +1,317 @@
+package prox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SetHTTPAddress makes the Server serve its HTTP API not only on the unix
+// socket but also on the given TCP address (e.g. "localhost:5555"). Since the
+// API does not require any authentication, only loopback addresses are
+// allowed. It must be called before Server.Run(…).
+func (s *Server) SetHTTPAddress(addr string) {
+	s.httpAddr = addr
+}
+
+// listenHTTP opens a TCP listener for the HTTP API on a loopback address.
+func listenHTTP(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid HTTP address")
+	}
+
+	if !isLoopbackHost(host) {
+		return nil, errors.Errorf("invalid HTTP address %q: only loopback addresses (e.g. localhost) are allowed", addr)
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// isLoopbackHost returns true if the host (without port) is "localhost" or a
+// loopback IP address.
+func isLoopbackHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// serveHTTP starts the http.Server of the HTTP API. It serves the HTTP
+// connections of the unix socket and (optionally) the TCP listener. All
+// requests are canceled when the context is done.
+func (s *Server) serveHTTP(ctx context.Context) {
+	s.httpConns = newConnListener(s.listener.Addr())
+	s.http = &http.Server{
+		Handler:     s.httpHandler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	listeners := []net.Listener{s.httpConns}
+	if s.httpListener != nil {
+		listeners = append(listeners, s.httpListener)
+	}
+
+	for _, l := range listeners {
+		s.wg.Add(1)
+		go func(l net.Listener) {
+			defer s.wg.Done()
+			err := s.http.Serve(l)
+			if err != nil && err != http.ErrServerClosed && !isClosedConnectionError(err) {
+				s.logger.Error("Failed to serve HTTP API", zap.Error(err))
+			}
+		}(l)
+	}
+}
+
+// httpHandler returns the handler of the HTTP API:
+//
+//	GET  /v1/processes                 list all processes
+//	GET  /v1/processes/<name>          get a single process
+//	POST /v1/processes/<name>/start    start a process that is not running
+//	POST /v1/processes/<name>/stop     stop a running process
+//	POST /v1/processes/<name>/restart  restart a process
+//	GET  /v1/logs                      stream output as server-sent events
+//	GET  /                             web dashboard
+//
+// The logs can be filtered via one or many "process" query parameters. The
+// "history" parameter controls how many recent lines are sent before the live
+// output (default 100). Clients that reconnect with a Last-Event-ID header
+// only receive the lines they have missed.
+func (s *Server) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/processes", s.handleHTTPProcesses)
+	mux.HandleFunc("/v1/processes/", s.handleHTTPProcess)
+	mux.HandleFunc("/v1/logs", s.handleHTTPLogs)
+	mux.HandleFunc("/", s.handleDashboard)
+	return protectHTTP(mux)
+}
+
+// protectHTTP rejects requests that a browser sends on behalf of other web
+// sites. Since the API is served on localhost without any authentication, a
+// malicious web site could otherwise control the processes (e.g. via a form
+// that posts to the API) or read their output via DNS rebinding.
+func protectHTTP(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// requests via the unix socket may use an arbitrary host
+		if _, isTCP := r.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr); isTCP {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if !isLoopbackHost(host) {
+				writeHTTPError(w, serverErrorf(CodeForbidden, "invalid host %q", r.Host))
+				return
+			}
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" && r.Method != http.MethodGet {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				writeHTTPError(w, serverErrorf(CodeForbidden, "cross-origin requests are not allowed"))
+				return
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// processResource is the representation of a process in the HTTP API.
+type processResource struct {
+	Name    string       `json:"name"`
+	State   ProcessState `json:"state"`
+	Color   string       `json:"color,omitempty"` // color of the output prefix (e.g. "cyan")
+	PID     int          `json:"pid,omitempty"`
+	Uptime  float64      `json:"uptime_seconds,omitempty"`
+	Runs    int          `json:"runs,omitempty"`
+	NextRun *time.Time   `json:"next_run,omitempty"`
+}
+
+func (s *Server) processResource(name string) processResource {
+	inf := s.Executor.Info(name)
+	r := processResource{
+		Name:  inf.Name,
+		State: inf.State,
+		Color: s.Executor.colorName(name),
+		Runs:  inf.Runs,
+	}
+
+	if inf.PID >= 0 {
+		r.PID = inf.PID
+		r.Uptime = inf.Uptime.Seconds()
+	}
+
+	if !inf.NextRun.IsZero() {
+		r.NextRun = &inf.NextRun
+	}
+
+	return r
+}
+
+func (s *Server) handleHTTPProcesses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeHTTPError(w, serverErrorf(CodeBadRequest, "method %s is not allowed", r.Method))
+		return
+	}
+
+	resp := []processResource{}
+	for _, name := range s.Executor.allProcesses() {
+		resp = append(resp, s.processResource(name))
+	}
+
+	writeHTTPResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleHTTPProcess(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/processes/"), "/")
+	parts := strings.Split(path, "/")
+	name := parts[0]
+
+	if !containsString(s.Executor.allProcesses(), name) {
+		writeHTTPError(w, noSuchProcessError(name))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeHTTPResponse(w, http.StatusOK, s.processResource(name))
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var err error
+		switch parts[1] {
+		case "start":
+			err = s.Executor.StartProcess(name)
+		case "stop":
+			err = s.Executor.StopProcess(name)
+		case "restart":
+			err = s.Executor.RestartProcess(name)
+		case "pause":
+			err = s.Executor.PauseProcess(name)
+		case "resume":
+			err = s.Executor.ResumeProcess(name)
+		default:
+			err = serverErrorf(CodeUnknownCommand, "unknown action %q", parts[1])
+		}
+
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeHTTPError(w, serverErrorf(CodeUnknownCommand, "unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// handleHTTPLogs streams the output of processes as server-sent events.
+func (s *Server) handleHTTPLogs(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, serverErrorf(CodeNotSupported, "streaming is not supported"))
+		return
+	}
+
+	query := r.URL.Query()
+	processes := query["process"]
+	for _, name := range processes {
+		if !containsString(s.Executor.allProcesses(), name) {
+			writeHTTPError(w, noSuchProcessError(name))
+			return
+		}
+	}
+
+	limit := 100
+	if h := query.Get("history"); h != "" {
+		n, err := strconv.Atoi(h)
+		if err != nil || n < 0 {
+			writeHTTPError(w, serverErrorf(CodeBadRequest, "invalid history %q", h))
+			return
+		}
+		limit = n
+	}
+
+	var since uint64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		since, _ = strconv.ParseUint(id, 10, 64)
+		limit = -1 // send everything the client has missed
+	}
+
+	match := func(name string) bool {
+		return len(processes) == 0 || containsString(processes, name)
+	}
+
+	history, lines, unsubscribe := s.Executor.history.subscribe(match, since, limit)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for _, l := range history {
+		writeSSE(w, l)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case l := <-lines:
+			writeSSE(w, l)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE writes a single line of output as server-sent event.
+func writeSSE(w http.ResponseWriter, l OutputLine) {
+	b, _ := json.Marshal(l)
+	fmt.Fprintf(w, "id: %d\nevent: output\ndata: %s\n\n", l.Seq, b)
+}
+
+func writeHTTPResponse(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// httpError is the body of all HTTP error responses.
+type httpError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+func writeHTTPError(w http.ResponseWriter, err error) {
+	code := errorCode(err)
+	status := http.StatusInternalServerError
+	switch code {
+	case CodeBadRequest:
+		status = http.StatusBadRequest
+	case CodeNotFound, CodeUnknownCommand:
+		status = http.StatusNotFound
+	case CodeConflict:
+		status = http.StatusConflict
+	case CodeNotSupported:
+		status = http.StatusNotImplemented
+	case CodeForbidden:
+		status = http.StatusForbidden
+	case CodeUnauthorized:
+		status = http.StatusUnauthorized
+	}
+
+	writeHTTPResponse(w, status, httpError{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,323 @@
+package prox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP API", func() {
+	var (
+		dir        string
+		socketPath string
+		server     *Server
+		runner     *countingRunner
+		output     chan string
+		httpClient *http.Client
+		cancel     func()
+		done       chan error
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "prox")
+		Expect(err).NotTo(HaveOccurred())
+
+		socketPath = filepath.Join(dir, "prox.sock")
+		server = NewExecutorServer(socketPath, true)
+		server.Executor.output = GinkgoWriter
+		runner = &countingRunner{starts: map[string]int{}}
+		output = make(chan string)
+
+		echo := Process{
+			Name: "echo",
+			Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+				for {
+					select {
+					case line := <-output:
+						fmt.Fprintln(w, line)
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}),
+		}
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan error, 1)
+		go func() {
+			done <- server.Run(ctx, []Process{echo, runner.process("p1")})
+		}()
+
+		httpClient = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		}}
+
+		Eventually(runner.Running).Should(ConsistOf("p1"))
+		Eventually(func() error {
+			resp, err := httpClient.Get("http://prox/v1/processes")
+			if err == nil {
+				resp.Body.Close()
+			}
+			return err
+		}).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(Receive())
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	get := func(path string, v interface{}) *http.Response {
+		resp, err := httpClient.Get("http://prox" + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(json.NewDecoder(resp.Body).Decode(v)).To(Succeed())
+		return resp
+	}
+
+	post := func(path string) *http.Response {
+		resp, err := httpClient.Post("http://prox"+path, "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		return resp
+	}
+
+	It("should list all processes", func() {
+		var processes []processResource
+		resp := get("/v1/processes", &processes)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(processes).To(HaveLen(2))
+		Expect(processes[0].Name).To(Equal("echo"))
+		Expect(processes[1].Name).To(Equal("p1"))
+		Expect(processes[1].State).To(Equal(StateRunning))
+		Expect(processes[0].Color).To(Equal("cyan"), "colors should match the colorPalette")
+		Expect(processes[1].Color).To(Equal("yellow"))
+	})
+
+	It("should return single processes", func() {
+		var p processResource
+		resp := get("/v1/processes/p1", &p)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(p.Name).To(Equal("p1"))
+		Expect(p.State).To(Equal(StateRunning))
+
+		var e httpError
+		resp = get("/v1/processes/unknown", &e)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(e).To(Equal(httpError{Code: CodeNotFound, Message: `no such process "unknown"`}))
+	})
+
+	It("should start, stop and restart processes", func() {
+		Expect(post("/v1/processes/p1/stop").StatusCode).To(Equal(http.StatusNoContent))
+		Eventually(runner.Running).Should(BeEmpty())
+		Eventually(func() ProcessState {
+			var p processResource
+			get("/v1/processes/p1", &p)
+			return p.State
+		}).Should(Equal(StateStopped))
+
+		Eventually(func() int {
+			return post("/v1/processes/p1/stop").StatusCode
+		}).Should(Equal(http.StatusConflict))
+
+		Expect(post("/v1/processes/p1/start").StatusCode).To(Equal(http.StatusNoContent))
+		Eventually(runner.Running).Should(ConsistOf("p1"))
+
+		Expect(post("/v1/processes/p1/restart").StatusCode).To(Equal(http.StatusNoContent))
+		Eventually(func() int { return runner.Starts("p1") }).Should(Equal(3))
+
+		Expect(post("/v1/processes/p1/explode").StatusCode).To(Equal(http.StatusNotFound))
+		Expect(post("/v1/processes/unknown/start").StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should stream the output as server-sent events", func() {
+		output <- "first line"
+		Eventually(func() []OutputLine {
+			history, _, unsubscribe := server.Executor.history.subscribe(func(string) bool { return true }, 0, -1)
+			unsubscribe()
+			return history
+		}).Should(HaveLen(1))
+
+		ctx, cancelRequest := context.WithCancel(context.Background())
+		defer cancelRequest()
+
+		req, err := http.NewRequest(http.MethodGet, "http://prox/v1/logs?process=echo", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := httpClient.Do(req.WithContext(ctx))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		events := bufio.NewReader(resp.Body)
+		readEvent := func() (id string, line OutputLine) {
+			var lines []string
+			for {
+				l, err := events.ReadString('\n')
+				Expect(err).NotTo(HaveOccurred())
+				if l == "\n" {
+					break
+				}
+				lines = append(lines, strings.TrimSuffix(l, "\n"))
+			}
+
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[1]).To(Equal("event: output"))
+			Expect(json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &line)).To(Succeed())
+			return strings.TrimPrefix(lines[0], "id: "), line
+		}
+
+		id, line := readEvent()
+		Expect(id).To(Equal("1"))
+		Expect(line.Process).To(Equal("echo"))
+		Expect(line.Line).To(Equal("first line"))
+
+		output <- "second line"
+		id, line = readEvent()
+		Expect(id).To(Equal("2"))
+		Expect(line.Line).To(Equal("second line"))
+	})
+
+	It("should resume the stream from the Last-Event-ID", func() {
+		output <- "line 1"
+		output <- "line 2"
+		output <- "line 3"
+		Eventually(func() int {
+			history, _, unsubscribe := server.Executor.history.subscribe(func(string) bool { return true }, 0, -1)
+			unsubscribe()
+			return len(history)
+		}).Should(Equal(3))
+
+		ctx, cancelRequest := context.WithCancel(context.Background())
+		defer cancelRequest()
+
+		req, err := http.NewRequest(http.MethodGet, "http://prox/v1/logs?history=0", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := httpClient.Do(req.WithContext(ctx))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		events := bufio.NewReader(resp.Body)
+		var ids []string
+		for len(ids) < 2 {
+			l, err := events.ReadString('\n')
+			Expect(err).NotTo(HaveOccurred())
+			if strings.HasPrefix(l, "id: ") {
+				ids = append(ids, strings.TrimSpace(strings.TrimPrefix(l, "id: ")))
+			}
+		}
+
+		Expect(ids).To(Equal([]string{"2", "3"}), "the client should receive all missed lines regardless of the history parameter")
+	})
+
+	It("should parse the level and tags of structured log messages", func() {
+		output <- `{"level":"error","msg":"boom"}`
+		output <- "plain text"
+
+		var history []OutputLine
+		Eventually(func() []OutputLine {
+			var unsubscribe func()
+			history, _, unsubscribe = server.Executor.history.subscribe(func(string) bool { return true }, 0, -1)
+			unsubscribe()
+			return history
+		}).Should(HaveLen(2))
+
+		Expect(history[0].Level).To(Equal("error"))
+		Expect(history[0].Tags).To(Equal([]string{"error"}))
+		Expect(history[1].Level).To(BeEmpty())
+		Expect(history[1].Tags).To(BeEmpty())
+	})
+
+	It("should serve the dashboard", func() {
+		resp, err := httpClient.Get("http://prox/")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(ContainSubstring("<title>prox</title>"))
+		Expect(string(body)).NotTo(MatchRegexp(`(src|href)="https?://`), "the dashboard should not load external assets")
+
+		var e httpError
+		resp = get("/unknown", &e)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should reject unknown processes when streaming logs", func() {
+		var e httpError
+		resp := get("/v1/logs?process=unknown", &e)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(e.Code).To(Equal(CodeNotFound))
+	})
+
+	It("should only listen on loopback addresses", func() {
+		_, err := listenHTTP("0.0.0.0:0")
+		Expect(err).To(MatchError(`invalid HTTP address "0.0.0.0:0": only loopback addresses (e.g. localhost) are allowed`))
+
+		_, err = listenHTTP(":0")
+		Expect(err).To(HaveOccurred())
+
+		l, err := listenHTTP("127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		l.Close()
+	})
+})
+
+var _ = Describe("protectHTTP", func() {
+	handler := protectHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(method, host, origin string, tcp bool) int {
+		req := httptest.NewRequest(method, "http://"+host+"/v1/processes/p1/stop", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if tcp {
+			addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5555}
+			req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, addr))
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	DescribeTable("requests",
+		func(method, host, origin string, tcp bool, expectedStatus int) {
+			Expect(serve(method, host, origin, tcp)).To(Equal(expectedStatus))
+		},
+		Entry("unix socket", "POST", "prox", "", false, http.StatusNoContent),
+		Entry("localhost", "POST", "localhost:5555", "", true, http.StatusNoContent),
+		Entry("loopback IP", "GET", "127.0.0.1:5555", "", true, http.StatusNoContent),
+		Entry("same origin", "POST", "localhost:5555", "http://localhost:5555", true, http.StatusNoContent),
+		Entry("other host (DNS rebinding)", "GET", "evil.example.com:5555", "", true, http.StatusForbidden),
+		Entry("cross origin", "POST", "localhost:5555", "http://evil.example.com", true, http.StatusForbidden),
+		Entry("cross origin via unix socket", "POST", "prox", "http://evil.example.com", false, http.StatusForbidden),
+	)
+})
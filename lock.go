@@ -0,0 +1,156 @@
+package prox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// An instanceLock makes sure that only a single prox server uses the same
+// unix socket at a time. The lock file contains the PID of the process that
+// holds the lock.
+type instanceLock struct {
+	path string
+	file *os.File
+}
+
+// acquireInstanceLock locks the file at the given path. If the file is locked
+// by another prox instance already, the returned error names its PID.
+func acquireInstanceLock(path string) (*instanceLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open lock file")
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		b, _ := ioutil.ReadAll(f)
+		f.Close()
+
+		if pid := strings.TrimSpace(string(b)); pid != "" {
+			return nil, errors.Errorf("prox is already running in this directory (PID %s)", pid)
+		}
+		return nil, errors.Errorf("prox is already running in this directory (%s is locked)", path)
+	}
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "failed to lock file")
+	}
+
+	// the lock file might still contain the PID of a crashed instance
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = fmt.Fprintln(f, os.Getpid())
+	}
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "failed to write PID to lock file")
+	}
+
+	return &instanceLock{path: path, file: f}, nil
+}
+
+// release removes the PID from the lock file and releases the lock. The lock
+// file itself is not removed because another instance might lock it in the
+// meantime while a third instance would create and lock a new file.
+func (l *instanceLock) release() error {
+	if l == nil {
+		return nil
+	}
+
+	l.file.Truncate(0)
+	return l.file.Close() // closing the file also releases the lock
+}
+
+// listenUnix opens a unix socket that is only accessible by the current user.
+// If there is a socket file left behind by a prox instance that has crashed,
+// it is removed first.
+//
+// The socket is created in a private directory and restricted before it is
+// moved to its path so other users can never connect to it.
+func listenUnix(path string) (net.Listener, error) {
+	if _, err := os.Lstat(path); err == nil {
+		err = removeStaleSocket(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".prox-socket-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create directory for socket")
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "prox.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+
+	// the socket is removed via its final path when the listener is closed
+	l.SetUnlinkOnClose(false)
+
+	err = os.Chmod(tmp, 0600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		l.Close()
+		return nil, errors.Wrap(err, "failed to create socket")
+	}
+
+	return &unixListener{UnixListener: l, path: path}, nil
+}
+
+// A unixListener is a unix socket listener that was moved to path after it
+// was created.
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+// Addr returns the final address of the socket.
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close closes the listener and removes its socket.
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// removeStaleSocket removes the socket at the given path unless some other
+// process is still listening on it. Files that are no sockets are never
+// removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to inspect socket")
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return errors.Errorf("%s exists but is no socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return errors.Errorf("socket %s is used by another process", path)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove stale socket")
+	}
+
+	return nil
+}
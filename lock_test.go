@@ -0,0 +1,165 @@
+package prox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Server socket", func() {
+	var (
+		dir        string
+		socketPath string
+		stops      []func()
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "prox")
+		Expect(err).NotTo(HaveOccurred())
+		socketPath = filepath.Join(dir, "prox.sock")
+	})
+
+	AfterEach(func() {
+		for _, stop := range stops {
+			stop()
+		}
+		stops = nil
+		os.RemoveAll(dir)
+	})
+
+	blocking := Process{
+		Name: "test",
+		Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}
+
+	run := func() (*Server, chan error) {
+		s := NewExecutorServer(socketPath, false)
+		s.Executor.output = NewBuffer()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		finished := make(chan bool)
+		go func() {
+			done <- s.Run(ctx, []Process{blocking})
+			close(finished)
+		}()
+
+		stops = append(stops, func() {
+			cancel()
+			<-finished
+			s.Close()
+		})
+
+		return s, done
+	}
+
+	It("should only be accessible by the current user", func() {
+		run()
+		Eventually(socketPath).Should(BeAnExistingFile())
+
+		info, err := os.Stat(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("should not start a second server on the same socket", func() {
+		run()
+		Eventually(socketPath).Should(BeAnExistingFile())
+
+		_, done := run()
+		Eventually(done).Should(Receive(MatchError(
+			fmt.Sprintf("prox is already running in this directory (PID %d)", os.Getpid()),
+		)))
+	})
+
+	It("should remove a stale socket of a crashed server", func() {
+		l, err := net.Listen("unix", socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+		Expect(socketPath).To(BeAnExistingFile())
+
+		_, done := run()
+		Eventually(func() error {
+			c, err := NewClient(socketPath, false)
+			if err == nil {
+				c.Close()
+			}
+			return err
+		}).Should(Succeed())
+		Consistently(done).ShouldNot(Receive())
+	})
+
+	It("should not remove the socket of another running process", func() {
+		l, err := net.Listen("unix", socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+
+		_, done := run()
+		Eventually(done).Should(Receive(MatchError(
+			fmt.Sprintf("failed to open unix socket: socket %s is used by another process", socketPath),
+		)))
+	})
+
+	It("should not remove a file that is no socket", func() {
+		Expect(ioutil.WriteFile(socketPath, []byte("important"), 0600)).To(Succeed())
+
+		_, done := run()
+		Eventually(done).Should(Receive(MatchError(
+			fmt.Sprintf("failed to open unix socket: %s exists but is no socket", socketPath),
+		)))
+
+		b, err := ioutil.ReadFile(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal("important"))
+	})
+
+	It("should remove the socket when it is closed", func() {
+		run()
+		Eventually(socketPath).Should(BeAnExistingFile())
+
+		stops[0]()
+		stops = nil
+		Expect(socketPath).NotTo(BeAnExistingFile())
+
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		for _, f := range files {
+			Expect(f.Name()).NotTo(HavePrefix(".prox-socket-"), "the temporary directory should be removed")
+		}
+	})
+
+	It("should release the lock when it is closed", func() {
+		run()
+		Eventually(socketPath + ".lock").Should(BeAnExistingFile())
+
+		stops[0]()
+		stops = nil
+
+		b, err := ioutil.ReadFile(socketPath + ".lock")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(BeEmpty(), "the lock file should not contain the PID anymore")
+
+		_, done := run()
+		Eventually(func() error {
+			c, err := NewClient(socketPath, false)
+			if err == nil {
+				c.Close()
+			}
+			return err
+		}).Should(Succeed())
+		Consistently(done).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,502 @@
+package prox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// The default limits of the log files of each process.
+const (
+	DefaultLogFileSize  = 10 << 20 // 10 MiB
+	DefaultLogFileCount = 3        // number of rotated files that are kept
+)
+
+// logTimeFormat is the format of the time at the beginning of every line in
+// the log files.
+const logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// logFollowInterval is the interval in which ReadLogs(…) checks for new lines
+// if it follows a log file.
+const logFollowInterval = 200 * time.Millisecond
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// logFiles persists the output of all processes in a directory. The output is
+// written synchronously by the output of each process (see logFileOutput) so
+// no line is lost. Every process has a log file with its formatted output (e.g.
+// "api.log") and one with its raw output (e.g. "api.raw.log"). Each line starts
+// with the time the process has emitted it. If a file would exceed the maximum
+// size it is rotated (e.g. to "api.log.1") so the logs of previous runs are
+// kept until they exceed the maximum number of files.
+type logFiles struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mu     sync.Mutex
+	procs  map[string]*processLogFiles
+	logger *zap.Logger
+}
+
+func newLogFiles(dir string, maxSize int64, maxFiles int) *logFiles {
+	return &logFiles{
+		dir:      dir,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		procs:    map[string]*processLogFiles{},
+		logger:   zap.NewNop(),
+	}
+}
+
+// add starts persisting the output of a process. If the process has been added
+// already (e.g. because its configuration was reloaded) its files are reopened
+// and its output is formatted according to the new configuration.
+func (l *logFiles) add(p Process) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if old, ok := l.procs[p.Name]; ok {
+		old.close()
+	}
+
+	pl := &processLogFiles{
+		raw:       &rotatingFile{path: l.path(p.Name, true), maxSize: l.maxSize, maxFiles: l.maxFiles},
+		formatted: &rotatingFile{path: l.path(p.Name, false), maxSize: l.maxSize, maxFiles: l.maxFiles},
+	}
+	pl.format = structured(&formattedOutput{Writer: pl}, p)
+	l.procs[p.Name] = pl
+}
+
+// remove stops persisting the output of a process.
+func (l *logFiles) remove(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if pl, ok := l.procs[name]; ok {
+		pl.close()
+		delete(l.procs, name)
+	}
+}
+
+// setLogger sets the logger that is used to report errors.
+func (l *logFiles) setLogger(logger *zap.Logger) {
+	l.mu.Lock()
+	l.logger = logger
+	l.mu.Unlock()
+}
+
+// close closes all open files. They are opened again if there is new output.
+func (l *logFiles) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, pl := range l.procs {
+		pl.close()
+	}
+}
+
+func (l *logFiles) path(name string, raw bool) string {
+	return logFilePath(l.dir, name, raw)
+}
+
+func logFilePath(dir, name string, raw bool) string {
+	if raw {
+		return filepath.Join(dir, name+".raw.log")
+	}
+
+	return filepath.Join(dir, name+".log")
+}
+
+// writeLine writes a line of output to the log files of the process.
+func (l *logFiles) writeLine(name, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pl, ok := l.procs[name]
+	if !ok {
+		return // the process was removed
+	}
+
+	err := pl.write(time.Now(), line)
+	if err != nil && !pl.failed {
+		pl.failed = true // only report the first error to not spam the output
+		l.logger.Error("Failed to write log file", zap.String("process", name), zap.Error(err))
+	}
+}
+
+// logFileOutput is an io.Writer that writes every line of a process to its log
+// files. It expects to receive complete lines so it should be wrapped into a
+// bufferedWriter. Errors are reported by the logFiles so Write never fails.
+type logFileOutput struct {
+	name  string
+	files *logFiles
+}
+
+func (o logFileOutput) Write(line []byte) (int, error) {
+	o.files.writeLine(o.name, string(bytes.TrimRight(line, "\r\n")))
+	return len(line), nil
+}
+
+// processLogFiles are the log files of a single process.
+type processLogFiles struct {
+	raw, formatted *rotatingFile
+	format         io.Writer // formats a raw line and writes it via processLogFiles.Write(…)
+	time           time.Time // the time of the line that is currently written
+	writeErr       error     // the last error of writing the formatted log file
+	failed         bool      // set once a line could not be written
+}
+
+func (pl *processLogFiles) write(t time.Time, line string) error {
+	pl.time = t
+	_, err := fmt.Fprintln(pl.raw, t.Format(logTimeFormat), line)
+	if err != nil {
+		return err
+	}
+
+	pl.writeErr = nil
+	_, err = pl.format.Write([]byte(line + "\n"))
+	if err != nil && pl.writeErr == nil {
+		// the line could not be decoded (e.g. because it is no JSON)
+		_, err = pl.Write([]byte(line + "\n"))
+	}
+
+	return err
+}
+
+// Write implements io.Writer by writing the formatted output without colors to
+// the formatted log file.
+func (pl *processLogFiles) Write(b []byte) (int, error) {
+	prefix := pl.time.Format(logTimeFormat) + " "
+	msg := ansiEscape.ReplaceAllString(strings.TrimSuffix(string(b), "\n"), "")
+
+	buf := new(bytes.Buffer)
+	for _, line := range strings.Split(msg, "\n") {
+		buf.WriteString(prefix + line + "\n")
+	}
+
+	_, pl.writeErr = pl.formatted.Write(buf.Bytes())
+	return len(b), pl.writeErr
+}
+
+func (pl *processLogFiles) close() {
+	pl.raw.Close()
+	pl.formatted.Close()
+}
+
+// A rotatingFile is an io.Writer that appends to a file and rotates it if it
+// would exceed its maximum size. The file is opened on the first write.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int // number of rotated files that are kept
+
+	file *os.File
+	size int64
+}
+
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	if f.file == nil {
+		err := f.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) open() error {
+	err := os.MkdirAll(filepath.Dir(f.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate renames the file to "<path>.1" after renaming all previously rotated
+// files to the next number, deleting the oldest file.
+func (f *rotatingFile) rotate() error {
+	f.Close()
+
+	for i := f.maxFiles - 1; i > 0; i-- {
+		err := os.Rename(rotatedPath(f.path, i), rotatedPath(f.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	var err error
+	if f.maxFiles > 0 {
+		err = os.Rename(f.path, rotatedPath(f.path, 1))
+	} else {
+		err = os.Remove(f.path)
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return f.open()
+}
+
+// Close closes the file if it is open.
+func (f *rotatingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func rotatedPath(path string, i int) string {
+	return path + "." + strconv.Itoa(i)
+}
+
+// LogOptions control which lines are written by ReadLogs(…).
+type LogOptions struct {
+	Since  time.Time // only write lines that were emitted at or after this time (if set)
+	Until  time.Time // only write lines that were emitted at or before this time (if set)
+	Raw    bool      // write the raw output instead of the formatted output
+	Follow bool      // wait for new lines until the context is done
+}
+
+// ReadLogs writes the persisted output of a process in the log directory to w,
+// starting with the oldest rotated file. The formatted output is written with
+// the time of each line while the raw output is written exactly as the process
+// has emitted it. This works regardless of whether prox is still running.
+func ReadLogs(ctx context.Context, dir, name string, opts LogOptions, w io.Writer) error {
+	path := logFilePath(dir, name, opts.Raw)
+	rotated, err := rotatedFiles(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) && len(rotated) == 0 {
+		return errors.Errorf("there are no logs of process %q in %s", name, dir)
+	}
+
+	for _, p := range rotated {
+		err := readLogFile(p, opts, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.Follow {
+		err := readLogFile(path, opts, w)
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil // the file was rotated and no new output was written so far
+		}
+		return err
+	}
+
+	return followLogFile(ctx, path, opts, w)
+}
+
+// rotatedFiles returns the paths of all rotated files of the log file at the
+// given path with the oldest file first.
+func rotatedFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := map[string]int{}
+	var rotated []string
+	for _, m := range matches {
+		i, err := strconv.Atoi(strings.TrimPrefix(m, path+"."))
+		if err != nil {
+			continue // e.g. "api.log" is no rotated file of "api"
+		}
+		numbers[m] = i
+		rotated = append(rotated, m)
+	}
+
+	sort.Slice(rotated, func(i, j int) bool {
+		return numbers[rotated[i]] > numbers[rotated[j]]
+	})
+
+	return rotated, nil
+}
+
+func readLogFile(path string, opts LogOptions, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			if err := writeLogLine(line, opts, w); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// followLogFile writes all lines of the log file and then waits for new lines
+// until the context is done. If the file is rotated, the remaining lines of the
+// old file are written before the new file is followed.
+func followLogFile(ctx context.Context, path string, opts LogOptions, w io.Writer) error {
+	var (
+		f       *os.File
+		r       *bufio.Reader
+		partial string // the beginning of a line that is currently written
+	)
+
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	// readLines writes all complete lines until the end of the file
+	readLines := func() error {
+		for r != nil {
+			line, err := r.ReadString('\n')
+			partial += line
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = writeLogLine(partial, opts, w)
+			partial = ""
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		if f == nil {
+			var err error
+			f, err = os.Open(path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if f != nil {
+				r = bufio.NewReader(f)
+			}
+		}
+
+		err := readLines()
+		if err != nil {
+			return err
+		}
+
+		if f != nil && rotated(f, path) {
+			// the old file may have received more lines before it was rotated
+			err := readLines()
+			if err == nil && partial != "" {
+				err = writeLogLine(partial, opts, w)
+			}
+			if err != nil {
+				return err
+			}
+
+			f.Close()
+			f, r, partial = nil, nil, ""
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logFollowInterval):
+		}
+	}
+}
+
+// rotated returns true if the file at the given path is not f anymore.
+func rotated(f *os.File, path string) bool {
+	current, err := os.Stat(path)
+	if err != nil {
+		return true
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return true
+	}
+
+	return !os.SameFile(info, current)
+}
+
+// writeLogLine writes a line of a log file if it matches the options.
+func writeLogLine(line string, opts LogOptions, w io.Writer) error {
+	line = strings.TrimSuffix(line, "\n")
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		i = len(line)
+	}
+
+	t, err := time.Parse(logTimeFormat, line[:i])
+	if err == nil {
+		if !opts.Since.IsZero() && t.Before(opts.Since) {
+			return nil
+		}
+		if !opts.Until.IsZero() && t.After(opts.Until) {
+			return nil
+		}
+	}
+
+	if opts.Raw && err == nil {
+		line = strings.TrimPrefix(line[i:], " ")
+	}
+
+	_, err = fmt.Fprintln(w, line)
+	return err
+}
@@ -0,0 +1,209 @@
+package prox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("logFiles", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "prox-logs")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	readFile := func(name string) []string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		Expect(err).NotTo(HaveOccurred())
+		return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	}
+
+	readLogs := func(name string, opts LogOptions) string {
+		buf := new(bytes.Buffer)
+		Expect(ReadLogs(context.Background(), dir, name, opts, buf)).To(Succeed())
+		return buf.String()
+	}
+
+	It("should persist the raw and the formatted output of each process", func() {
+		l := newLogFiles(dir, DefaultLogFileSize, DefaultLogFileCount)
+		l.add(Process{Name: "api"})
+		l.add(Process{Name: "db"})
+
+		l.writeLine("api", `{"level":"error","msg":"boom","code":42}`)
+		l.writeLine("api", "plain text")
+		l.writeLine("db", "ready")
+		l.writeLine("unknown", "ignored")
+		l.close()
+
+		raw := readFile("api.raw.log")
+		Expect(raw).To(HaveLen(2))
+		Expect(raw[0]).To(MatchRegexp(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}\S* {"level":"error","msg":"boom","code":42}$`))
+		Expect(raw[1]).To(HaveSuffix(" plain text"))
+
+		formatted := readFile("api.log")
+		Expect(formatted).To(HaveLen(2))
+		Expect(formatted[0]).To(HaveSuffix(" [ERROR]\tboom\t{ \"code\": 42 }"), "the output should not contain colors")
+		Expect(formatted[1]).To(HaveSuffix(" plain text"))
+
+		Expect(readFile("db.log")[0]).To(HaveSuffix(" ready"))
+		Expect(filepath.Join(dir, "unknown.log")).NotTo(BeAnExistingFile())
+
+		Expect(readLogs("api", LogOptions{Raw: true})).To(Equal("{\"level\":\"error\",\"msg\":\"boom\",\"code\":42}\nplain text\n"))
+		Expect(readLogs("api", LogOptions{})).To(ContainSubstring(" [ERROR]\tboom"))
+	})
+
+	It("should rotate the log files and keep the output of previous runs", func() {
+		l := newLogFiles(dir, 100, 2)
+		l.add(Process{Name: "api"})
+
+		for i := 0; i < 10; i++ {
+			l.writeLine("api", strings.Repeat("x", 30))
+		}
+		l.close()
+
+		Expect(filepath.Join(dir, "api.raw.log.1")).To(BeAnExistingFile())
+		Expect(filepath.Join(dir, "api.raw.log.2")).To(BeAnExistingFile())
+		Expect(filepath.Join(dir, "api.raw.log.3")).NotTo(BeAnExistingFile())
+
+		for _, name := range []string{"api.raw.log", "api.raw.log.1", "api.raw.log.2"} {
+			info, err := os.Stat(filepath.Join(dir, name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Size()).To(BeNumerically("<=", 100))
+		}
+
+		// a new run appends to the existing files
+		l = newLogFiles(dir, 100, 2)
+		l.add(Process{Name: "api"})
+		l.writeLine("api", "next run")
+		l.close()
+
+		lines := strings.Split(strings.TrimSpace(readLogs("api", LogOptions{Raw: true})), "\n")
+		Expect(len(lines)).To(BeNumerically(">", 3))
+		Expect(lines[len(lines)-1]).To(Equal("next run"))
+	})
+
+	It("should filter the output by time", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "api.log"), []byte(
+			"2018-12-09T15:00:00.000Z first\n"+
+				"2018-12-09T15:05:00.000Z second\n"+
+				"2018-12-09T15:10:00.000Z third\n",
+		), 0600)).To(Succeed())
+
+		opts := LogOptions{
+			Since: time.Date(2018, 12, 9, 15, 5, 0, 0, time.UTC),
+			Until: time.Date(2018, 12, 9, 15, 9, 0, 0, time.UTC),
+		}
+		Expect(readLogs("api", opts)).To(Equal("2018-12-09T15:05:00.000Z second\n"))
+	})
+
+	It("should return an error if there are no logs of a process", func() {
+		err := ReadLogs(context.Background(), dir, "api", LogOptions{}, ioutil.Discard)
+		Expect(err).To(MatchError(`there are no logs of process "api" in ` + dir))
+	})
+
+	It("should follow the output across rotations", func() {
+		l := newLogFiles(dir, 50, 1)
+		l.add(Process{Name: "api"})
+		l.writeLine("api", "line 1")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		output := new(syncBuffer)
+		done := make(chan error)
+		go func() {
+			done <- ReadLogs(ctx, dir, "api", LogOptions{Raw: true, Follow: true}, output)
+		}()
+
+		Eventually(output.String).Should(Equal("line 1\n"))
+		for i := 2; i <= 4; i++ {
+			line := fmt.Sprintf("line %d (%s)", i, strings.Repeat("x", 20))
+			l.writeLine("api", line)
+			Eventually(output.String).Should(HaveSuffix(line + "\n"))
+		}
+		l.close()
+
+		Expect(filepath.Join(dir, "api.raw.log.1")).To(BeAnExistingFile(), "the file should have been rotated")
+		Expect(strings.Count(output.String(), "\n")).To(Equal(4))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should be used by the Executor", func() {
+		e := TestNewExecutor(GinkgoWriter)
+		e.SetLogDir(dir, DefaultLogFileSize, DefaultLogFileCount)
+
+		p1 := &TestProcess{name: "p1"}
+		go e.Run(p1)
+		Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+		p1.ShouldSay(GinkgoT(), "hello\n")
+		Eventually(func() string {
+			b, _ := ioutil.ReadFile(filepath.Join(dir, "p1.raw.log"))
+			return string(b)
+		}).Should(HaveSuffix(" hello\n"))
+
+		p1.Finish()
+		Eventually(e.IsDone).Should(BeTrue())
+	})
+
+	It("should persist all lines even if an observer is too slow", func() {
+		e := TestNewExecutor(GinkgoWriter)
+		e.SetLogDir(dir, DefaultLogFileSize, DefaultLogFileCount)
+
+		slow := &recordingObserver{block: make(chan bool)}
+		e.AddObserver(slow)
+		e.observers[len(e.observers)-1].limit = 1
+
+		p1 := &TestProcess{name: "p1"}
+		go e.Run(p1)
+		Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+		t := GinkgoT()
+		for i := 0; i < 10; i++ {
+			p1.ShouldSay(t, fmt.Sprintf("line %d\n", i))
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, "p1.raw.log"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Count(string(b), "\n")).To(Equal(10))
+		Expect(string(b)).To(HaveSuffix(" line 9\n"))
+
+		close(slow.block)
+		p1.Finish()
+		Eventually(e.IsDone).Should(BeTrue())
+	})
+})
+
+// syncBuffer is a bytes.Buffer that can be used concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
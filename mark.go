@@ -0,0 +1,116 @@
+package prox
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A MarkStyle determines how the output of a marked process is highlighted.
+type MarkStyle string
+
+// All styles of a Mark.
+const (
+	MarkGutter     MarkStyle = "gutter"     // a heavy bar (┃) instead of the separator after the process name
+	MarkBackground MarkStyle = "background" // the message is printed on the background color of the process
+)
+
+// A Mark highlights the output of a process in the output of the Executor
+// (see Executor.Mark).
+type Mark struct {
+	Style MarkStyle
+
+	// Where optionally restricts the mark to lines whose fields match all
+	// conditions in the form "key=value" or "key=/regex/flags" (see
+	// TailOptions.Where).
+	Where []string
+}
+
+// A processMark is a validated Mark.
+type processMark struct {
+	style  MarkStyle
+	filter *lineFilter // nil if all lines are marked
+}
+
+// newProcessMark validates a Mark. If no style is given, MarkGutter is used.
+func newProcessMark(m Mark) (*processMark, error) {
+	pm := &processMark{style: m.Style}
+	switch m.Style {
+	case "":
+		pm.style = MarkGutter
+	case MarkGutter, MarkBackground:
+	default:
+		return nil, errors.Errorf("unknown mark style %q", m.Style)
+	}
+
+	if len(m.Where) > 0 {
+		f, err := newLineFilter("", "", m.Where)
+		if err != nil {
+			return nil, err
+		}
+		pm.filter = f
+	}
+
+	return pm, nil
+}
+
+// Mark highlights the output of the process with the given name until it is
+// unmarked. An existing mark of the process is replaced. Marks are kept when
+// the process is restarted.
+func (e *Executor) Mark(name string, m Mark) error {
+	pm, err := newProcessMark(m)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.configs[name]; !ok {
+		return noSuchProcessError(name)
+	}
+
+	e.marks[name] = pm
+	e.replaceFormattedOutput(name)
+	return nil
+}
+
+// Unmark removes the mark of the process with the given name.
+func (e *Executor) Unmark(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.configs[name]; !ok {
+		return noSuchProcessError(name)
+	}
+
+	delete(e.marks, name)
+	e.replaceFormattedOutput(name)
+	return nil
+}
+
+// replaceFormattedOutput formats the output of a process again with its
+// current configuration and mark. Processes that have no output yet receive
+// their mark when they are started. The caller must hold e.mu.
+func (e *Executor) replaceFormattedOutput(name string) {
+	po, ok := e.outputs[name]
+	if !ok {
+		return
+	}
+
+	po.replaceFirst(e.out.formatted(e.configs[name], e.colors[name], e.marks[name]))
+}
+
+// markedOutput decides for every line of output whether it is marked before
+// it is formatted and written via out.
+type markedOutput struct {
+	io.Writer
+	out    *formattedOutput
+	filter *lineFilter
+}
+
+func (o *markedOutput) Write(line []byte) (int, error) {
+	o.out.marked = o.filter == nil || o.filter.match(OutputLine{Line: strings.TrimSpace(string(line))})
+	return o.Writer.Write(line)
+}
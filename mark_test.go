@@ -0,0 +1,154 @@
+package prox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Marks", func() {
+	Describe("output", func() {
+		var (
+			buffer *bytes.Buffer
+			o      *output
+		)
+
+		BeforeEach(func() {
+			buffer = new(bytes.Buffer)
+			o = &output{writer: newSyncWriter(buffer), prefixLength: 8}
+		})
+
+		mark := func(m Mark) *processMark {
+			pm, err := newProcessMark(m)
+			Expect(err).NotTo(HaveOccurred())
+			return pm
+		}
+
+		It("should print a gutter instead of the separator", func() {
+			w := o.formatted(Process{Name: "test"}, colorNone, mark(Mark{Style: MarkGutter}))
+			w.Write([]byte("This is a log message\n"))
+
+			Expect(buffer.String()).To(Equal("test     ┃ This is a log message\n"))
+		})
+
+		It("should print the message on the background color of the process", func() {
+			w := o.formatted(Process{Name: "test"}, colorCyan, mark(Mark{Style: MarkBackground}))
+			w.Write([]byte("This is a log message\n"))
+
+			prefix := colorDefault + colorBold + colorCyan + "test     │ " + colorDefault
+			bg := color("\x1b[46m")
+			Expect(buffer.String()).To(BeEquivalentTo(prefix + bg + "This is a log message" + colorDefault + "\n"))
+		})
+
+		It("should use the gutter if the output has no colors", func() {
+			w := o.formatted(Process{Name: "test"}, colorNone, mark(Mark{Style: MarkBackground}))
+			w.Write([]byte("This is a log message\n"))
+
+			Expect(buffer.String()).To(Equal("test     ┃ This is a log message\n"))
+		})
+
+		It("should only mark lines that match the conditions", func() {
+			w := o.formatted(Process{Name: "test"}, colorNone, mark(Mark{Where: []string{"host=foobar"}}))
+			w.Write([]byte(`{"msg":"first","host":"foobar"}` + "\n"))
+			w.Write([]byte(`{"msg":"second","host":"other"}` + "\n"))
+
+			Expect(buffer.String()).To(ContainSubstring("test     ┃ first"))
+			Expect(buffer.String()).To(ContainSubstring("test     │ second"))
+		})
+
+		It("should reject invalid marks", func() {
+			_, err := newProcessMark(Mark{Style: "blink"})
+			Expect(err).To(MatchError(`unknown mark style "blink"`))
+
+			_, err = newProcessMark(Mark{Where: []string{"host"}})
+			Expect(err).To(MatchError(`invalid condition "host": expected "key=value"`))
+		})
+	})
+
+	Describe("via the Client", func() {
+		var (
+			dir    string
+			output *Buffer
+			lines  chan string
+			client *Client
+			cancel context.CancelFunc
+			done   chan error
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "prox")
+			Expect(err).NotTo(HaveOccurred())
+
+			output = NewBuffer()
+			server := NewExecutorServer(filepath.Join(dir, "prox.sock"), true)
+			server.Executor.output = output
+			server.DisableColoredOutput()
+
+			lines = make(chan string)
+			p := Process{
+				Name: "api",
+				Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+					for {
+						select {
+						case l := <-lines:
+							fmt.Fprintln(w, l)
+						case <-ctx.Done():
+							return nil
+						}
+					}
+				}),
+			}
+
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan error, 1)
+			go func() {
+				done <- server.Run(ctx, []Process{p})
+				server.Close()
+			}()
+
+			Eventually(func() error {
+				client, err = NewClient(filepath.Join(dir, "prox.sock"), false)
+				return err
+			}).Should(Succeed())
+		})
+
+		AfterEach(func() {
+			client.Close()
+			cancel()
+			Eventually(done).Should(Receive())
+			os.RemoveAll(dir)
+		})
+
+		It("should mark and unmark the output of processes", func() {
+			ctx := context.Background()
+			lines <- "before"
+			Eventually(output).Should(Say(`api      │ before\n`))
+
+			Expect(client.Mark(ctx, Mark{Style: MarkGutter}, "a*")).To(Equal([]string{"api"}))
+			lines <- "marked"
+			Eventually(output).Should(Say(`api      ┃ marked\n`))
+
+			Expect(client.Unmark(ctx, "api")).To(Equal([]string{"api"}))
+			lines <- "after"
+			Eventually(output).Should(Say(`api      │ after\n`))
+		})
+
+		It("should return an error for invalid marks and unknown processes", func() {
+			_, err := client.Mark(context.Background(), Mark{Style: "blink"}, "api")
+			Expect(err).To(Equal(&ServerError{Code: CodeBadRequest, Message: `unknown mark style "blink"`}))
+
+			_, err = client.Unmark(context.Background(), "worker")
+			Expect(err).To(Equal(&ServerError{Code: CodeNotFound, Message: `no such process "worker"`}))
+		})
+	})
+})
@@ -0,0 +1,204 @@
+package prox
+
+import (
+	"bytes"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// maxPendingNotifications is the number of notifications that are queued for
+// a single Observer before further lines of output are dropped.
+const maxPendingNotifications = 10000
+
+// An Observer can be registered at an Executor to be notified about the
+// lifecycle and output of all processes it runs.
+//
+// Callbacks are delivered asynchronously: the callbacks of a single Observer
+// are called sequentially from a dedicated goroutine in the same order in which
+// the corresponding events happened, but the Executor never waits for them
+// while it is running processes, so a slow Observer does not block any process
+// output.
+//
+// Delivery of ProcessOutput is lossy. If more than maxPendingNotifications
+// callbacks are pending for an Observer, further lines of output are dropped
+// until the Observer has caught up and the Executor logs a warning with the
+// number of dropped lines. All other callbacks are never dropped. When the
+// Executor finishes, it waits until all pending callbacks have been delivered.
+type Observer interface {
+	// ProcessStarted is called when a process is started.
+	ProcessStarted(name string)
+
+	// ProcessExited is called when a process has finished along with the
+	// reason why it has finished and the error it has returned (if any).
+	ProcessExited(name string, status ExitStatus, err error)
+
+	// ProcessOutput is called for each line the process has written to its
+	// stdout or stderr. The trailing new line is not part of the line.
+	ProcessOutput(name, line string)
+
+	// Shutdown is called once when the Executor starts to shut down all
+	// processes (e.g. because a process failed or the context was canceled).
+	Shutdown()
+}
+
+// A ReadinessObserver is an Observer that is additionally notified when a
+// process has become ready (see Process.Ready).
+type ReadinessObserver interface {
+	Observer
+	ProcessReady(name string)
+}
+
+// An ExitStatus indicates why a process has finished.
+type ExitStatus int
+
+// All statuses that are reported by the Executor.
+const (
+	StatusSuccess     ExitStatus = iota // process finished with error code 0
+	StatusError                         // process failed with some error
+	StatusInterrupted                   // process was cancelled because the context interrupted
+)
+
+// String implements fmt.Stringer.
+func (s ExitStatus) String() string {
+	switch s {
+	case StatusSuccess:
+		return "success"
+	case StatusError:
+		return "error"
+	case StatusInterrupted:
+		return "interrupted"
+	default:
+		return "unknown"
+	}
+}
+
+// observerQueue delivers notifications to a single Observer in order without
+// blocking the caller that enqueues them. If the Observer falls behind by more
+// than limit notifications, new lossy notifications (i.e. output) are dropped.
+type observerQueue struct {
+	observer Observer
+	limit    int
+	done     chan struct{}
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []func(Observer)
+	dropped int // notifications dropped since the queue was last drained
+	closed  bool
+	logger  *zap.Logger // may be nil
+}
+
+func newObserverQueue(o Observer, logger *zap.Logger) *observerQueue {
+	q := &observerQueue{
+		observer: o,
+		limit:    maxPendingNotifications,
+		done:     make(chan struct{}),
+		logger:   logger,
+	}
+
+	q.cond = sync.NewCond(&q.mu)
+	go q.deliver()
+	return q
+}
+
+// setLogger sets the logger that is used to warn about dropped notifications.
+func (q *observerQueue) setLogger(logger *zap.Logger) {
+	q.mu.Lock()
+	q.logger = logger
+	q.mu.Unlock()
+}
+
+// push enqueues a new notification. It never blocks on the Observer. Lossy
+// notifications are dropped if the queue is full while all other notifications
+// are always enqueued.
+func (q *observerQueue) push(f func(Observer), lossy bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case q.closed:
+		return
+	case lossy && q.limit >= 0 && len(q.pending) >= q.limit:
+		q.dropped++
+		if q.dropped == 1 && q.logger != nil {
+			q.logger.Warn("Observer is too slow, dropping output", zap.Int("pending", len(q.pending)))
+		}
+	default:
+		q.pending = append(q.pending, f)
+		q.cond.Signal()
+	}
+}
+
+// close stops accepting new notifications and blocks until all pending
+// notifications have been delivered.
+func (q *observerQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Signal()
+	q.mu.Unlock()
+
+	<-q.done
+}
+
+func (q *observerQueue) deliver() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			return
+		}
+
+		f := q.pending[0]
+		q.pending[0] = nil
+		q.pending = q.pending[1:]
+		if len(q.pending) == 0 && q.dropped > 0 {
+			if q.logger != nil {
+				q.logger.Warn("Observer has caught up after dropping output", zap.Int("dropped_lines", q.dropped))
+			}
+			q.dropped = 0
+		}
+		q.mu.Unlock()
+
+		f(q.observer)
+	}
+}
+
+// observedOutput is an io.Writer that notifies all observers of an Executor
+// about every line a process writes. It expects to receive complete lines so
+// it should be wrapped into a bufferedWriter.
+type observedOutput struct {
+	name     string
+	executor *Executor
+}
+
+func (o observedOutput) Write(line []byte) (int, error) {
+	return o.WriteStream(line, StreamStdout)
+}
+
+// WriteStream implements the streamWriter interface by passing the stream to
+// all observers that are interested in it.
+func (o observedOutput) WriteStream(line []byte, stream string) (int, error) {
+	s := string(bytes.TrimRight(line, "\r\n"))
+	o.executor.notifyOutput(func(obs Observer) {
+		if so, ok := obs.(streamObserver); ok {
+			so.processStreamOutput(o.name, stream, s)
+			return
+		}
+
+		obs.ProcessOutput(o.name, s)
+	})
+
+	return len(line), nil
+}
+
+// A streamObserver is an Observer that also receives the stream (i.e. stdout
+// or stderr) of each line of output.
+type streamObserver interface {
+	processStreamOutput(name, stream, line string)
+}
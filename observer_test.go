@@ -0,0 +1,143 @@
+package prox
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fgrosse/zaptest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Observer", func() {
+	var (
+		executor *TestExecutor
+		observer *recordingObserver
+	)
+
+	BeforeEach(func() {
+		executor = TestNewExecutor(GinkgoWriter)
+		observer = new(recordingObserver)
+		executor.AddObserver(observer)
+	})
+
+	It("should be notified about the lifecycle and output of all processes in order", func() {
+		p1 := &TestProcess{name: "p1"}
+		p2 := &TestProcess{name: "p2"}
+
+		go executor.Run(p1, p2)
+		EventuallyAllProcessesShouldHaveStarted(p1, p2)
+		Eventually(observer.Calls).Should(ConsistOf("started p1", "started p2"))
+
+		t := GinkgoT()
+		p1.ShouldSay(t, "first line\nsecond line\n")
+		p1.Fail()
+
+		Eventually(executor.IsDone).Should(BeTrue())
+		Expect(observer.Calls()[2:]).To(Equal([]string{
+			"output p1: first line",
+			"output p1: second line",
+			"exited p1: error (TestProcess simulated a failure)",
+			"shutdown",
+			"exited p2: interrupted (context canceled)",
+		}))
+	})
+
+	It("should not block the process output", func() {
+		observer.block = make(chan bool)
+
+		p1 := &TestProcess{name: "p1"}
+		go executor.Run(p1)
+		EventuallyAllProcessesShouldHaveStarted(p1)
+
+		t := GinkgoT()
+		for i := 0; i < 100; i++ {
+			p1.ShouldSay(t, fmt.Sprintf("line %d\n", i))
+		}
+
+		Consistently(observer.Calls).Should(BeEmpty())
+		close(observer.block)
+
+		p1.Finish()
+		Eventually(executor.IsDone).Should(BeTrue())
+		Expect(observer.Calls()).To(HaveLen(103))
+		Expect(observer.Calls()[100]).To(Equal("output p1: line 99"))
+	})
+
+	It("should only drop output if the observer falls too far behind", func() {
+		observer.block = make(chan bool)
+		logs := NewBuffer()
+		q := newObserverQueue(observer, zaptest.LoggerWriter(logs))
+		q.limit = 3
+
+		// the first notification is delivered and blocks the observer
+		q.push(func(o Observer) { o.ProcessStarted("p1") }, false)
+		Eventually(func() int {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			return len(q.pending)
+		}).Should(BeZero())
+
+		for i := 0; i < 5; i++ {
+			line := fmt.Sprintf("line %d", i)
+			q.push(func(o Observer) { o.ProcessOutput("p1", line) }, true)
+		}
+		Eventually(logs).Should(Say("Observer is too slow, dropping output"))
+
+		q.push(func(o Observer) { o.ProcessExited("p1", StatusSuccess, nil) }, false)
+		q.push(func(o Observer) { o.Shutdown() }, false)
+
+		close(observer.block)
+		Eventually(logs).Should(Say(`Observer has caught up after dropping output.*"dropped_lines": 2`))
+
+		q.close()
+		Expect(observer.Calls()).To(Equal([]string{
+			"started p1",
+			"output p1: line 0",
+			"output p1: line 1",
+			"output p1: line 2",
+			"exited p1: success (<nil>)",
+			"shutdown",
+		}))
+	})
+})
+
+type recordingObserver struct {
+	block chan bool // optionally blocks all callbacks until it is closed
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (o *recordingObserver) ProcessStarted(name string) {
+	o.record("started %s", name)
+}
+
+func (o *recordingObserver) ProcessExited(name string, status ExitStatus, err error) {
+	o.record("exited %s: %s (%v)", name, status, err)
+}
+
+func (o *recordingObserver) ProcessOutput(name, line string) {
+	o.record("output %s: %s", name, line)
+}
+
+func (o *recordingObserver) Shutdown() {
+	o.record("shutdown")
+}
+
+func (o *recordingObserver) record(format string, args ...interface{}) {
+	if o.block != nil {
+		<-o.block
+	}
+
+	o.mu.Lock()
+	o.calls = append(o.calls, fmt.Sprintf(format, args...))
+	o.mu.Unlock()
+}
+
+func (o *recordingObserver) Calls() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.calls...)
+}
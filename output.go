@@ -130,29 +130,86 @@ func (o *output) next(p Process) *multiWriter {
 
 // nextColored is like output.next(…) but allows to set the color directly.
 func (o *output) nextColored(p Process, c color) *multiWriter {
+	return newMultiWriter(o.formatted(p, c, nil))
+}
+
+// formatted creates the writer that formats and prefixes all output of p. If
+// m is not nil, the lines that match the mark are highlighted.
+func (o *output) formatted(p Process, c color, m *processMark) io.Writer {
 	out := &formattedOutput{Writer: o.writer}
-	name := p.Name + strings.Repeat(" ", o.prefixLength-len(p.Name))
+	name := p.Name
+	if n := o.prefixLength - len(p.Name); n > 0 {
+		// processes that have been added later (e.g. via reload) may have a
+		// name that is longer than the prefix length
+		name += strings.Repeat(" ", n)
+	}
+
+	out.prefix = prefix(name, " │ ", c)
+
+	w := structured(out, p)
+	if m != nil {
+		out.style = m.style
+		out.markedPrefix = prefix(name, " ┃ ", c)
+		out.background = background(c)
+		w = &markedOutput{Writer: w, out: out, filter: m.filter}
+	}
+
+	return newBufferedProcessOutput(w)
+}
+
+// prefix returns the prefix of every line of output of a process.
+func prefix(name, separator string, c color) string {
 	if c == colorNone {
-		out.prefix = name + " │ "
-	} else {
-		out.prefix = fmt.Sprint(colorDefault, colorBold, c, name, " │ ", colorDefault)
+		return name + separator
 	}
 
+	return fmt.Sprint(colorDefault, colorBold, c, name, separator, colorDefault)
+}
+
+// structured creates the writer that decodes the structured log messages of p
+// (if there are any) and writes them formatted via out. The returned writer
+// expects a single complete line on each write.
+func structured(out io.Writer, p Process) io.Writer {
 	if p.Output.Format == "" {
 		p.Output = DefaultStructuredOutput(p.Env)
 	}
 
-	var w io.Writer = out
 	switch p.Output.Format {
 	case "json":
-		jo := newProcessJSONOutput(out, p.Output)
-		w = newBufferedProcessOutput(jo)
+		return newProcessJSONOutput(out, p.Output)
 	default:
-		ao := newProcessAutoDetectOutput(out, p.Output)
-		w = newBufferedProcessOutput(ao)
+		return newProcessAutoDetectOutput(out, p.Output)
+	}
+}
+
+// The output streams of a process.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// A streamWriter is an io.Writer that also receives the stream (i.e. stdout or
+// stderr) to which the process has written its output.
+type streamWriter interface {
+	WriteStream(p []byte, stream string) (int, error)
+}
+
+// writeStream writes p via w and passes the stream if w supports it.
+func writeStream(w io.Writer, p []byte, stream string) (int, error) {
+	if sw, ok := w.(streamWriter); ok {
+		return sw.WriteStream(p, stream)
 	}
 
-	return newMultiWriter(w)
+	return w.Write(p)
+}
+
+// stderrOutput is the io.Writer that receives the stderr output of a process.
+type stderrOutput struct {
+	io.Writer
+}
+
+func (o stderrOutput) Write(p []byte) (int, error) {
+	return writeStream(o.Writer, p, StreamStderr)
 }
 
 type multiWriter struct {
@@ -185,16 +242,30 @@ func (mw *multiWriter) RemoveWriter(w io.Writer) {
 	mw.mu.Unlock()
 }
 
+// replaceFirst replaces the first writer of mw (i.e. the formatted output that
+// was passed to newMultiWriter(…)) while keeping all other writers.
+func (mw *multiWriter) replaceFirst(w io.Writer) {
+	mw.mu.Lock()
+	mw.writers[0] = w
+	mw.mu.Unlock()
+}
+
 // Write implements io.writer by writing p via all its writers. This function
 // returns without an error if at least one of the writers has written the
 // message without an error.
 func (mw *multiWriter) Write(p []byte) (int, error) {
+	return mw.WriteStream(p, StreamStdout)
+}
+
+// WriteStream is like Write but passes the stream to all writers that support
+// it.
+func (mw *multiWriter) WriteStream(p []byte, stream string) (int, error) {
 	var lastErr error
 	var ok bool
 
 	mw.mu.Lock()
 	for _, w := range mw.writers {
-		n, err := w.Write(p)
+		n, err := writeStream(w, p, stream)
 		if err != nil {
 			lastErr = err
 			continue
@@ -221,6 +292,12 @@ func (mw *multiWriter) Write(p []byte) (int, error) {
 type formattedOutput struct {
 	io.Writer
 	prefix string
+
+	// The following fields are only set if the process is marked.
+	marked       bool      // whether the current line is marked
+	style        MarkStyle // the style of marked lines
+	markedPrefix string    // the prefix of marked lines in the MarkGutter style
+	background   color     // the background of marked lines in the MarkBackground style
 }
 
 // Write implements io.writer by formatting b and writing it through os wrapped
@@ -232,44 +309,83 @@ func (o *formattedOutput) Write(b []byte) (int, error) {
 }
 
 func (o *formattedOutput) formatMsg(p []byte) string {
+	prefix, background := o.prefix, colorNone
+	switch {
+	case !o.marked:
+	case o.style == MarkBackground && o.background != colorNone:
+		background = o.background
+	default:
+		// without colors there is no background so we use the gutter instead
+		prefix = o.markedPrefix
+	}
+
 	msg := new(bytes.Buffer)
 	for _, line := range bytes.Split(bytes.TrimSpace(p), []byte("\n")) {
 		if msg.Len() > 0 {
 			msg.WriteString("\n")
 		}
-		fmt.Fprint(msg, o.prefix, string(line))
+
+		if background == colorNone {
+			fmt.Fprint(msg, prefix, string(line))
+			continue
+		}
+
+		// colored parts of the line must not reset the background
+		line = bytes.ReplaceAll(line, []byte(colorDefault), []byte(colorDefault+background))
+		fmt.Fprint(msg, prefix, background, string(line), colorDefault)
 	}
 
 	return msg.String()
 }
 
 // a bufferedWriter is an io.Writer that buffers written messages until the next
-// new line character and then writes every line via its embedded writer.
+// new line character and then writes every line via its embedded writer. The
+// output of each stream is buffered separately so lines of stdout and stderr
+// are never mixed.
 type bufferedWriter struct {
-	io.Writer               // the writer we are eventually emitting our output to
-	buffer    *bytes.Buffer // contains all bytes written up to the next new line
+	io.Writer                          // the writer we are eventually emitting our output to
+	buffers   map[string]*bytes.Buffer // contains all bytes written up to the next new line per stream
 }
 
 func newBufferedProcessOutput(w io.Writer) io.Writer {
-	b := new(bytes.Buffer)
 	return &bufferedWriter{
-		Writer: w,
-		buffer: b,
+		Writer:  w,
+		buffers: map[string]*bytes.Buffer{},
 	}
 }
 
 func (o *bufferedWriter) Write(p []byte) (int, error) {
+	return o.WriteStream(p, StreamStdout)
+}
+
+// WriteStream implements the streamWriter interface by buffering p separately
+// for each stream and passing the stream of each line to the embedded writer.
+func (o *bufferedWriter) WriteStream(p []byte, stream string) (int, error) {
+	buffer, ok := o.buffers[stream]
+	if !ok {
+		buffer = new(bytes.Buffer)
+		o.buffers[stream] = buffer
+	}
+
 	for i, r := range p {
-		err := o.buffer.WriteByte(r)
+		err := buffer.WriteByte(r)
 		if err != nil {
 			return i, err
 		}
 
-		if r == '\n' {
-			_, err := io.Copy(o.Writer, o.buffer)
-			if err != nil {
-				return i, err
-			}
+		if r != '\n' {
+			continue
+		}
+
+		if sw, ok := o.Writer.(streamWriter); ok {
+			_, err = sw.WriteStream(buffer.Bytes(), stream)
+			buffer.Reset()
+		} else {
+			_, err = io.Copy(o.Writer, buffer)
+		}
+
+		if err != nil {
+			return i, err
 		}
 	}
 
@@ -355,20 +471,30 @@ func newProcessJSONOutput(w io.Writer, conf StructuredOutput) *processJSONOutput
 
 var valueRegex = regexp.MustCompile("/(.+)/(.*)")
 
+// parseValueRegex parses a regular expression which is surrounded by slashes
+// and optionally followed by flags (e.g. "/foo|bar/i"). If the value is no such
+// expression, nil is returned.
+func parseValueRegex(value string) *regexp.Regexp {
+	matches := valueRegex.FindStringSubmatch(value)
+	if matches == nil {
+		return nil
+	}
+
+	reStr := matches[1]
+	if strings.ContainsRune(matches[2], 'i') {
+		reStr = "(?i)" + reStr
+	}
+
+	re, _ := regexp.Compile(reStr)
+	return re
+}
+
 // addTaggingRule adds a new tagging rule to o. The `tag` is applied to each
 // message which contains a certain `field` where the corresponding value is
 // equal to the given `value`. Optionally the value can be a regular expression
 // by surrounding it with slashes (e.g. /foo|bar/i).
 func (o *processJSONOutput) addTaggingRule(field, value, tag string) {
-	var re *regexp.Regexp
-	if matches := valueRegex.FindStringSubmatch(value); matches != nil {
-		reStr := matches[1]
-		if strings.ContainsRune(matches[2], 'i') {
-			reStr = "(?i)" + reStr
-		}
-		re, _ = regexp.Compile(reStr)
-	}
-
+	re := parseValueRegex(value)
 	o.taggingRules = append(o.taggingRules, func(m map[string]interface{}) string {
 		if re != nil && re.MatchString(o.stringField(m, field)) {
 			return tag
@@ -392,15 +518,50 @@ func (o *processJSONOutput) setTagAction(tag string, action tagAction) {
 	o.tagActions[tag] = action
 }
 
-func (o *processJSONOutput) Write(line []byte) (int, error) {
-	m := map[string]interface{}{}
-	err := json.Unmarshal(line, &m)
+// parse decodes a single JSON log message and returns its fields, its level
+// and all tags that apply to it.
+func (o *processJSONOutput) parse(line []byte) (fields map[string]interface{}, level string, tags []string, err error) {
+	err = json.Unmarshal(line, &fields)
+	if err != nil {
+		return nil, "", nil, errors.Wrap(err, "parsing JSON message")
+	}
+
+	return fields, o.stringField(fields, o.levelField), o.applyTags(fields), nil
+}
+
+// a structuredMessage is a decoded structured log message.
+type structuredMessage struct {
+	message string
+	level   string
+	fields  map[string]interface{} // all fields except the message and level
+	tags    []string
+	color   color // the color of the message according to its tags
+}
+
+// decode parses a single JSON log message and splits it into its message, its
+// level and all other fields.
+func (o *processJSONOutput) decode(line []byte) (structuredMessage, error) {
+	m, lvl, tags, err := o.parse(line)
 	if err != nil {
-		return 0, errors.Wrap(err, "parsing JSON message")
+		return structuredMessage{}, err
 	}
 
+	msg := o.stringField(m, o.messageField)
+	delete(m, o.messageField)
+	delete(m, o.levelField)
+
+	return structuredMessage{
+		message: msg,
+		level:   lvl,
+		fields:  m,
+		tags:    tags,
+		color:   o.tagColor(tags),
+	}, nil
+}
+
+// tagColor returns the color of the last tag which has a color action.
+func (o *processJSONOutput) tagColor(tags []string) color {
 	var col color
-	tags := o.applyTags(m)
 	for _, t := range tags {
 		action, ok := o.tagActions[t]
 		if !ok {
@@ -412,31 +573,46 @@ func (o *processJSONOutput) Write(line []byte) (int, error) {
 		}
 	}
 
-	msg := o.stringField(m, o.messageField)
-	lvl := o.stringField(m, o.levelField)
-	delete(m, o.messageField)
-	delete(m, o.levelField)
+	return col
+}
 
-	if lvl != "" {
-		msg = fmt.Sprintf("[%s]\t%s", strings.ToUpper(lvl), msg)
+func (o *processJSONOutput) Write(line []byte) (int, error) {
+	m, err := o.decode(line)
+	if err != nil {
+		return 0, err
 	}
 
-	if len(m) > 0 {
-		extra, err := o.prettyJSON(m)
-		if err != nil {
-			return 0, err
-		}
-		msg = msg + "\t" + extra
+	msg, err := m.format()
+	if err != nil {
+		return 0, err
 	}
 
-	if col != "" {
-		msg = colored(col, msg)
+	if m.color != "" {
+		msg = colored(m.color, msg)
 	}
 
 	_, err = o.Writer.Write([]byte(msg + "\n"))
 	return len(line), err
 }
 
+// format returns the level, the message and all other fields in a single line.
+func (m structuredMessage) format() (string, error) {
+	msg := m.message
+	if m.level != "" {
+		msg = fmt.Sprintf("[%s]\t%s", strings.ToUpper(m.level), msg)
+	}
+
+	if len(m.fields) > 0 {
+		extra, err := prettyJSON(m.fields)
+		if err != nil {
+			return "", err
+		}
+		msg = msg + "\t" + extra
+	}
+
+	return msg, nil
+}
+
 // stringField attempts to extract a string field stored under the given key in
 // the map. The empty string is returned if no such key exists in m or if its
 // value is not a string.
@@ -465,7 +641,7 @@ func (o *processJSONOutput) applyTags(m map[string]interface{}) []string {
 }
 
 // prettyJSON marshals i into a JSON pretty printed single line format.
-func (*processJSONOutput) prettyJSON(i interface{}) (string, error) {
+func prettyJSON(i interface{}) (string, error) {
 	b, err := json.MarshalIndent(i, "", "")
 	if err != nil {
 		return "", err
@@ -262,3 +262,44 @@ func writeLine(w io.Writer, s string) {
 	_, err := w.Write([]byte(s + "\n"))
 	Expect(err).NotTo(HaveOccurred())
 }
+
+var _ = Describe("bufferedWriter", func() {
+	It("should buffer the output of each stream separately", func() {
+		w := new(streamRecorder)
+		b := newBufferedProcessOutput(w).(*bufferedWriter)
+
+		b.Write([]byte("first "))
+		b.WriteStream([]byte("an error\n"), StreamStderr)
+		b.Write([]byte("line\nsecond line\n"))
+
+		Expect(w.lines).To(Equal([]string{
+			"stderr: an error\n",
+			"stdout: first line\n",
+			"stdout: second line\n",
+		}))
+	})
+
+	It("should pass the stream of stderrOutput through a multiWriter", func() {
+		w := new(streamRecorder)
+		mw := newMultiWriter(newBufferedProcessOutput(w))
+
+		stderrOutput{mw}.Write([]byte("an error\n"))
+		mw.Write([]byte("a message\n"))
+
+		Expect(w.lines).To(Equal([]string{"stderr: an error\n", "stdout: a message\n"}))
+	})
+})
+
+// streamRecorder is a streamWriter that records all lines with their stream.
+type streamRecorder struct {
+	lines []string
+}
+
+func (r *streamRecorder) Write(p []byte) (int, error) {
+	return r.WriteStream(p, "unknown")
+}
+
+func (r *streamRecorder) WriteStream(p []byte, stream string) (int, error) {
+	r.lines = append(r.lines, stream+": "+string(p))
+	return len(p), nil
+}
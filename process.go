@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"regexp"
 	"strings"
@@ -23,15 +24,75 @@ type Process struct {
 	Script string
 	Env    Environment
 	Output StructuredOutput // optional
+
+	// Timeout is the optional maximum runtime of the process. StartTimeout is
+	// the optional maximum time until the process must be ready. A process is
+	// ready as soon as a line of its output matches the Ready condition which
+	// is either a plain string or a regular expression like "/listening/i".
+	// Without a Ready condition, the first line of output makes it ready.
+	Timeout      time.Duration
+	StartTimeout time.Duration
+	Ready        string
+
+	// Schedule optionally starts the process periodically instead of once. It
+	// is either a cron expression or an interval (see ParseSchedule). Overlap
+	// determines what happens if the process is due while it is still running
+	// (default: OverlapSkip).
+	Schedule string
+	Overlap  OverlapPolicy
+
+	// Runner is an optional custom implementation of the process. If it is set
+	// the Executor runs it instead of starting the Script as shell process.
+	Runner Runner `json:"-"`
+}
+
+// A Runner is a custom process implementation that can be run by the Executor
+// next to normal shell processes (e.g. an in-process Go function, a fake
+// service in tests or a command that is executed on a remote machine). Its
+// output and lifecycle is handled exactly like the one of a shell process.
+//
+// If the Runner also implements Info() ProcessInfo, it is used to report
+// information about the running process (e.g. via `prox ls`).
+type Runner interface {
+	// Run executes the process and blocks until it has finished or the
+	// context is done. All output of the process must be written to the given
+	// writer. If the process was stopped because the context was canceled,
+	// Run should return the error of the context.
+	Run(ctx context.Context, output io.Writer) error
+}
+
+// The RunnerFunc type is an adapter to allow the use of ordinary functions as
+// Runner.
+type RunnerFunc func(ctx context.Context, output io.Writer) error
+
+// Run implements the Runner interface by calling f(ctx, output).
+func (f RunnerFunc) Run(ctx context.Context, output io.Writer) error {
+	return f(ctx, output)
 }
 
 // ProcessInfo contains information about a running process.
 type ProcessInfo struct {
 	Name   string
+	State  ProcessState
 	PID    int
 	Uptime time.Duration
+
+	// NextRun and Runs are only set for scheduled processes.
+	NextRun time.Time
+	Runs    int
 }
 
+// A ProcessState describes whether a process is currently running.
+type ProcessState string
+
+// All states a process can be in.
+const (
+	StateRunning   ProcessState = "running"
+	StatePaused    ProcessState = "paused"    // running but frozen via Executor.PauseProcess
+	StateScheduled ProcessState = "scheduled" // not running but waiting for its next run
+	StateStopped   ProcessState = "stopped"
+)
+
 // Validate checks if all given processes are valid and no process name is used
 // multiple times. If an error is returned it will be a multierror.
 func Validate(pp []Process) error {
@@ -70,10 +131,33 @@ func (p Process) Validate() error {
 		errs = multierror.Append(errs, errors.New("missing name"))
 	}
 
-	if strings.TrimSpace(p.Script) == "" {
+	if strings.TrimSpace(p.Script) == "" && p.Runner == nil {
 		errs = multierror.Append(errs, errors.New("missing script"))
 	}
 
+	if p.Timeout < 0 {
+		errs = multierror.Append(errs, errors.New("timeout must not be negative"))
+	}
+
+	if p.StartTimeout < 0 {
+		errs = multierror.Append(errs, errors.New("start timeout must not be negative"))
+	}
+
+	if p.Schedule != "" {
+		if _, err := ParseSchedule(p.Schedule); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	switch p.Overlap {
+	case "", OverlapSkip, OverlapQueue:
+		if p.Overlap != "" && p.Schedule == "" {
+			errs = multierror.Append(errs, errors.New("overlap policy requires a schedule"))
+		}
+	default:
+		errs = multierror.Append(errs, errors.Errorf("unknown overlap policy %q", p.Overlap))
+	}
+
 	switch p.Output.Format {
 	case "", "auto":
 		// using default values, nothing to check
@@ -154,7 +238,8 @@ func (p *systemProcess) Info() ProcessInfo {
 
 // Run starts the shell process and blocks until it finishes or the context is
 // done. The systemProcess.output receives both the stdout and stderr output
-// of the process.
+// of the process. The output of stderr is marked as such if the output is a
+// streamWriter.
 func (p *systemProcess) Run(ctx context.Context) error {
 	p.mu.Lock()
 
@@ -167,9 +252,13 @@ func (p *systemProcess) Run(ctx context.Context) error {
 	p.cmd = exec.Command("env", args...)
 
 	p.cmd.Stdout = p.output
-	p.cmd.Stderr = p.output
+	p.cmd.Stderr = stderrOutput{p.output}
 	p.cmd.Env = p.env.List()
 
+	// Start every process in its own process group so we can signal the
+	// process and all of its children without also signaling prox itself.
+	p.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
 	p.startedAt = time.Now()
 	err = p.cmd.Start()
 	p.mu.Unlock()
@@ -203,34 +292,32 @@ func (p *systemProcess) wait(ctx context.Context) error {
 		}
 		return err
 	case <-ctx.Done():
-		if p.cmd.ProcessState != nil && p.cmd.ProcessState.Exited() {
+		select {
+		case <-done:
 			// There is nothing to do anymore so we can return early.
 			return ctx.Err()
+		default:
 		}
 
 		p.logger.Info("Sending interrupt signal", zap.Duration("timeout", p.interruptTimeout))
 
-		/*
-			TODO: to kill all child processes as well try this:
-			group, err := os.FindProcess(-1 * p.Process.Pid)
-			if err == nil {
-				err = group.Signal(signal)
-			}
-		*/
-
-		// TODO: this results in our child processes to receive SIGINT twice, due to the process group issue (e.g. visible in redis)
-		err := p.cmd.Process.Signal(syscall.SIGINT)
-		if err != nil && err.Error() != "os: process already finished" {
+		// Since every process runs in its own process group we must send the
+		// signal to the whole group in order to reach all child processes.
+		err := p.signalGroup(syscall.SIGINT)
+		if err != nil && err != syscall.ESRCH {
 			p.logger.Error("Failed to send SIGINT to process", zap.Error(err))
-			p.cmd.Process.Kill()
+			p.signalGroup(syscall.SIGKILL)
 			return ctx.Err()
 		}
 
+		// A paused process can only handle the interrupt once it continues.
+		p.signalGroup(syscall.SIGCONT)
+
 		select {
 		case <-done:
 			p.logger.Debug("Process interrupted successfully", zap.Error(err))
 		case <-time.After(p.interruptTimeout):
-			err := p.cmd.Process.Kill()
+			err := p.signalGroup(syscall.SIGKILL)
 			if err != nil {
 				p.logger.Error("Failed to kill process", zap.Error(err))
 			}
@@ -240,6 +327,89 @@ func (p *systemProcess) wait(ctx context.Context) error {
 	}
 }
 
+// Signal sends a signal to the process. If group is true, the signal is sent
+// to the whole process group (i.e. also to all child processes).
+func (p *systemProcess) Signal(sig syscall.Signal, group bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil || p.cmd.Process == nil {
+		return errors.New("process is not running")
+	}
+
+	if group {
+		return syscall.Kill(-p.cmd.Process.Pid, sig)
+	}
+
+	return p.cmd.Process.Signal(sig)
+}
+
+// signalGroup sends a signal to the process group of p.
+func (p *systemProcess) signalGroup(sig syscall.Signal) error {
+	return syscall.Kill(-p.cmd.Process.Pid, sig)
+}
+
+// newProcess creates the process implementation for p. Processes which have a
+// custom Runner are run via a runnerProcess and all others are started as
+// systemProcess.
+func newProcess(p Process, output io.Writer, logger *zap.Logger) process {
+	if p.Runner != nil {
+		return newRunnerProcess(p.Name, p.Runner, output)
+	}
+
+	return newSystemProcess(p.Name, p.Script, p.Env, output, logger)
+}
+
+// a runnerProcess is a process implementation that delegates to a custom
+// Runner.
+type runnerProcess struct {
+	name   string
+	runner Runner
+	output io.Writer
+
+	mu        sync.Mutex
+	startedAt time.Time
+}
+
+func newRunnerProcess(name string, r Runner, output io.Writer) *runnerProcess {
+	return &runnerProcess{name: name, runner: r, output: output}
+}
+
+// Name returns the human readable name of p that can be used to identify a
+// specific process.
+func (p *runnerProcess) Name() string {
+	return p.name
+}
+
+// Info returns the information of the Runner if it implements an Info()
+// function. Otherwise p is treated like a process that runs within prox.
+func (p *runnerProcess) Info() ProcessInfo {
+	if r, ok := p.runner.(interface{ Info() ProcessInfo }); ok {
+		return r.Info()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.startedAt.IsZero() {
+		return ProcessInfo{PID: -1}
+	}
+
+	return ProcessInfo{
+		PID:    os.Getpid(),
+		Uptime: time.Since(p.startedAt),
+	}
+}
+
+// Run runs the Runner and blocks until it has finished.
+func (p *runnerProcess) Run(ctx context.Context) error {
+	p.mu.Lock()
+	p.startedAt = time.Now()
+	p.mu.Unlock()
+
+	return p.runner.Run(ctx, p.output)
+}
+
 func (p *systemProcess) parseCommandLine() ([]string, error) {
 	var (
 		args         []string
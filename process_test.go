@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -63,11 +64,29 @@ var _ = Describe("Process", func() {
 			Expect(p.Validate()).To(Succeed())
 		})
 
+		It("should not require a script if a custom Runner is set", func() {
+			p := Process{Name: "test", Runner: RunnerFunc(func(context.Context, io.Writer) error { return nil })}
+			Expect(p.Validate()).To(Succeed())
+		})
+
 		It("should not require any explicit fields when using the 'auto' log format", func() {
 			p := Process{Name: "test", Script: "echo test"}
 			p.Output.Format = "auto"
 			Expect(p.Validate()).To(Succeed())
 		})
+
+		It("should return an error if the schedule is invalid", func() {
+			p := Process{Name: "test", Script: "echo test", Schedule: "every day"}
+			Expect(p.Validate()).To(MatchError(ContainSubstring(`invalid schedule "every day"`)))
+		})
+
+		It("should return an error if the overlap policy is unknown or has no schedule", func() {
+			p := Process{Name: "test", Script: "echo test", Schedule: "@hourly", Overlap: "parallel"}
+			Expect(p.Validate()).To(MatchError(ContainSubstring(`unknown overlap policy "parallel"`)))
+
+			p = Process{Name: "test", Script: "echo test", Overlap: OverlapQueue}
+			Expect(p.Validate()).To(MatchError(ContainSubstring("overlap policy requires a schedule")))
+		})
 	})
 })
 
@@ -449,6 +468,7 @@ func echoProcess(args []string) {
 	blocking := fs.Bool("block", false, "do not return after printing")
 	noSigInt := fs.Bool("no-sigint", false, "ignore SIGINT when blocking")
 	quote := fs.Bool("quote", false, "quote al arguments")
+	exitCode := fs.Int("exit", 0, "exit code to use after receiving a signal when blocking")
 
 	err := fs.Parse(args)
 	if err != nil {
@@ -500,6 +520,8 @@ func echoProcess(args []string) {
 				break
 			}
 		}
+
+		os.Exit(*exitCode)
 	}
 
 	os.Exit(0)
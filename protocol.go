@@ -0,0 +1,168 @@
+package prox
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/fgrosse/prox/proxpb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ProtocolVersion is the version of the protocol that is spoken between a
+// Client and a Server. Versions 1 and 2 were the line based protocols of prox
+// versions prior to the gRPC service. Version 3 is the prox.v1 gRPC service
+// (see proxpb/prox.proto) which only receives backwards compatible changes.
+const ProtocolVersion = 3
+
+// minProtocolVersion is the oldest protocol version that is still supported.
+const minProtocolVersion = 3
+
+// versionMetadataKey is the key of the gRPC metadata that contains the
+// protocol version of a request (sent by the Client) or a response (sent by
+// the Server).
+const versionMetadataKey = "prox-protocol-version"
+
+// instanceMetadataKey is the key of the gRPC metadata of a Tail stream that
+// contains the instance of the output history of the Server. Clients use it to
+// resume a stream via proxpb.TailRequest.SinceSeq.
+const instanceMetadataKey = "prox-instance"
+
+// versionMetadata returns the metadata with the protocol version.
+func versionMetadata() metadata.MD {
+	return metadata.Pairs(versionMetadataKey, strconv.Itoa(ProtocolVersion))
+}
+
+// checkVersion checks that the protocol version in the metadata of the other
+// side is supported. Metadata without a version is accepted because clients
+// of the first prox.v1 release did not send it.
+func checkVersion(md metadata.MD) error {
+	for _, v := range md.Get(versionMetadataKey) {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < minProtocolVersion || version > ProtocolVersion {
+			return unsupportedVersionError(v)
+		}
+	}
+
+	return nil
+}
+
+// unsupportedVersionError is returned if the protocol version of the other
+// side is not supported.
+func unsupportedVersionError(version string) error {
+	return serverErrorf(CodeUnsupportedVersion,
+		"unsupported protocol version %s: supported versions are %d-%d (upgrade prox on both sides)",
+		version, minProtocolVersion, ProtocolVersion,
+	)
+}
+
+// An ErrorCode classifies the errors that are returned by the Server.
+type ErrorCode string
+
+// All error codes that are returned by the Server.
+const (
+	CodeUnsupportedVersion ErrorCode = "unsupported_version" // the protocol versions of client and server are incompatible
+	CodeUnknownCommand     ErrorCode = "unknown_command"     // the server does not know the command
+	CodeBadRequest         ErrorCode = "bad_request"         // the arguments of the command are invalid
+	CodeNotFound           ErrorCode = "not_found"           // a process does not exist
+	CodeNotSupported       ErrorCode = "not_supported"       // the server does not support the command
+	CodeConflict           ErrorCode = "conflict"            // the command conflicts with the state of a process
+	CodeForbidden          ErrorCode = "forbidden"           // the HTTP request was sent on behalf of another web site
+	CodeUnauthorized       ErrorCode = "unauthorized"        // a remote client has sent an invalid token
+	CodeFailed             ErrorCode = "failed"              // the command was valid but has failed
+)
+
+// A ServerError is returned by the Client if the Server has responded to a
+// command with an error.
+type ServerError struct {
+	Code    ErrorCode
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ServerError) Error() string {
+	return e.Message
+}
+
+// serverErrorf creates a new *ServerError.
+func serverErrorf(code ErrorCode, format string, args ...interface{}) error {
+	return &ServerError{Code: code, Message: errors.Errorf(format, args...).Error()}
+}
+
+// errorCode returns the ErrorCode that classifies the given error.
+func errorCode(err error) ErrorCode {
+	switch e := errors.Cause(err).(type) {
+	case *ServerError:
+		return e.Code
+	case noSuchProcessError:
+		return CodeNotFound
+	case processStateError:
+		return CodeConflict
+	default:
+		return CodeFailed
+	}
+}
+
+// grpcCode maps an ErrorCode to the closest gRPC status code.
+func grpcCode(code ErrorCode) codes.Code {
+	switch code {
+	case CodeUnsupportedVersion, CodeUnknownCommand, CodeNotSupported:
+		return codes.Unimplemented
+	case CodeBadRequest:
+		return codes.InvalidArgument
+	case CodeNotFound:
+		return codes.NotFound
+	case CodeConflict:
+		return codes.FailedPrecondition
+	case CodeForbidden:
+		return codes.PermissionDenied
+	case CodeUnauthorized:
+		return codes.Unauthenticated
+	default:
+		return codes.Unknown
+	}
+}
+
+// grpcError converts an error into a gRPC status error. The ErrorCode is
+// attached as proxpb.Error to the details of the status.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err // already a status (e.g. because the context was canceled)
+	}
+
+	code := errorCode(err)
+	st, detailErr := status.New(grpcCode(code), err.Error()).WithDetails(&proxpb.Error{Code: string(code)})
+	if detailErr != nil {
+		return status.Error(grpcCode(code), err.Error())
+	}
+
+	return st.Err()
+}
+
+// fromGRPCError converts a gRPC status error that was returned by the Server
+// into a *ServerError. If the request was canceled or has timed out, the
+// corresponding context error is returned. Other errors that were not sent by
+// the Server (e.g. because the connection was closed) are returned unchanged.
+func fromGRPCError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+
+	for _, d := range st.Details() {
+		if e, ok := d.(*proxpb.Error); ok {
+			return &ServerError{Code: ErrorCode(e.Code), Message: st.Message()}
+		}
+	}
+
+	switch st.Code() {
+	case codes.Canceled:
+		return context.Canceled
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	default:
+		return err
+	}
+}
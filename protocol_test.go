@@ -0,0 +1,188 @@
+package prox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fgrosse/prox/proxpb"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Protocol", func() {
+	DescribeTable("error conversion",
+		func(err error, expectedCode codes.Code, expected *ServerError) {
+			st, ok := status.FromError(grpcError(err))
+			Expect(ok).To(BeTrue())
+			Expect(st.Code()).To(Equal(expectedCode))
+			Expect(fromGRPCError(st.Err())).To(Equal(expected))
+		},
+
+		Entry("server error", serverErrorf(CodeBadRequest, "bad signal"),
+			codes.InvalidArgument, &ServerError{Code: CodeBadRequest, Message: "bad signal"},
+		),
+		Entry("unknown process", noSuchProcessError("foo"),
+			codes.NotFound, &ServerError{Code: CodeNotFound, Message: `no such process "foo"`},
+		),
+		Entry("process state", processStateError{"process \"foo\" is not running"},
+			codes.FailedPrecondition, &ServerError{Code: CodeConflict, Message: `process "foo" is not running`},
+		),
+		Entry("other errors", errors.New("boom"),
+			codes.Unknown, &ServerError{Code: CodeFailed, Message: "boom"},
+		),
+	)
+
+	It("should convert output lines with their structured log message", func() {
+		l := OutputLine{
+			Seq:      7,
+			Time:     time.Now().Round(0),
+			Process:  "api",
+			Stream:   StreamStderr,
+			Line:     `{"level":"error","msg":"boom","req":{"id":"abc"}}`,
+			Color:    "cyan",
+			Dropped:  3,
+			Message:  "boom",
+			Level:    "error",
+			Fields:   map[string]interface{}{"req": map[string]interface{}{"id": "abc"}},
+			Tags:     []string{"error"},
+			TagColor: "red-bold",
+		}
+		Expect(outputLineFromProto(outputLineToProto(l))).To(Equal(l))
+
+		plain := OutputLine{Seq: 8, Time: l.Time, Process: "api", Stream: StreamStdout, Line: "plain"}
+		Expect(outputLineFromProto(outputLineToProto(plain)).Fields).To(BeNil())
+	})
+
+	It("should check the protocol version of the other side", func() {
+		Expect(checkVersion(versionMetadata())).To(Succeed())
+		Expect(checkVersion(metadata.MD{})).To(Succeed(), "clients of the first prox.v1 release do not send a version")
+		Expect(checkVersion(metadata.Pairs(versionMetadataKey, "2"))).To(MatchError(ContainSubstring("unsupported protocol version 2")))
+		Expect(checkVersion(metadata.Pairs(versionMetadataKey, "foo"))).To(MatchError(ContainSubstring("unsupported protocol version foo")))
+
+		Expect(checkServerVersion(metadata.MD{}, nil)).To(Succeed())
+		Expect(checkServerVersion(metadata.MD{}, status.Error(codes.Unimplemented, "unknown method"))).To(MatchError(ContainSubstring("unsupported protocol version")))
+	})
+
+	It("should not convert errors that were not sent by the server", func() {
+		err := status.Error(codes.Unavailable, "connection closed")
+		Expect(fromGRPCError(err)).To(Equal(err))
+		Expect(fromGRPCError(nil)).To(BeNil())
+	})
+
+	It("should return context errors if a request was canceled or has timed out", func() {
+		Expect(fromGRPCError(status.Error(codes.Canceled, "context canceled"))).To(Equal(context.Canceled))
+		Expect(fromGRPCError(status.Error(codes.DeadlineExceeded, "context deadline exceeded"))).To(Equal(context.DeadlineExceeded))
+	})
+})
+
+var _ = Describe("Server errors", func() {
+	It("should respond with an error to unknown processes", func() {
+		t := GinkgoT()
+		_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+		defer done()
+
+		p1 := &TestProcess{name: "p1"}
+		go executor.Run(p1)
+		Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+		err := tail(context.Background(), client, []string{"p1", "unknown"}, TailOptions{}, GinkgoWriter)
+		Expect(err).To(Equal(&ServerError{Code: CodeNotFound, Message: `cannot tail unknown process "unknown"`}))
+	})
+
+	It("should return context.Canceled if the context of a request is canceled", func() {
+		t := GinkgoT()
+		_, client, _, done := TestNewServerAndClient(t, GinkgoWriter)
+		defer done()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.List(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("should respond with an error to unknown commands", func() {
+		t := GinkgoT()
+		server, client, _, done := TestNewServerAndClient(t, GinkgoWriter)
+		defer done()
+
+		conn, err := grpc.Dial(server.listener.Addr().String(), grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		err = conn.Invoke(context.Background(), "/prox.v1.Prox/Foo", new(proxpb.ListRequest), new(proxpb.ListResponse))
+		Expect(status.Code(err)).To(Equal(codes.Unimplemented))
+
+		// the Client cannot know whether the server is too old
+		err = client.conn.Invoke(context.Background(), "/prox.v1.Prox/Foo", new(proxpb.ListRequest), new(proxpb.ListResponse))
+		Expect(err).To(BeAssignableToTypeOf(&ServerError{}))
+		Expect(err.(*ServerError).Code).To(Equal(CodeUnsupportedVersion))
+	})
+
+	It("should respond with an error to unsupported protocol versions", func() {
+		t := GinkgoT()
+		server, client, _, done := TestNewServerAndClient(t, GinkgoWriter)
+		defer done()
+
+		conn, err := grpc.Dial(server.listener.Addr().String(), grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(context.Background(), versionMetadataKey, "99")
+		_, err = proxpb.NewProxClient(conn).List(ctx, new(proxpb.ListRequest), grpc.Header(&header))
+		Expect(fromGRPCError(err)).To(Equal(&ServerError{
+			Code:    CodeUnsupportedVersion,
+			Message: "unsupported protocol version 99: supported versions are 3-3 (upgrade prox on both sides)",
+		}))
+		Expect(header.Get(versionMetadataKey)).To(Equal([]string{"3"}))
+
+		_, err = client.List(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should respond with an error to invalid arguments", func() {
+		t := GinkgoT()
+		_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+		defer done()
+
+		p1 := &TestProcess{name: "p1"}
+		go executor.Run(p1)
+		Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+		_, err := client.Signal(context.Background(), []string{"p1"}, "SIGFOO", false)
+		Expect(err).To(BeAssignableToTypeOf(&ServerError{}))
+		Expect(err.(*ServerError).Code).To(Equal(CodeBadRequest))
+
+		err = tail(context.Background(), client, nil, TailOptions{}, GinkgoWriter)
+		Expect(err).To(Equal(&ServerError{Code: CodeBadRequest, Message: "no processes to tail"}))
+
+		err = tail(context.Background(), client, []string{"p1"}, TailOptions{Level: "loud"}, GinkgoWriter)
+		Expect(err).To(Equal(&ServerError{Code: CodeBadRequest, Message: `unknown level "loud"`}))
+	})
+
+	It("should close all connections when the server is closed", func() {
+		t := GinkgoT()
+		server, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+		defer done()
+
+		p1 := &TestProcess{name: "p1"}
+		go executor.Run(p1)
+		Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+		tailing := make(chan error)
+		go func() {
+			tailing <- tail(context.Background(), client, []string{"p1"}, TailOptions{}, GinkgoWriter)
+		}()
+
+		Consistently(tailing).ShouldNot(Receive())
+		Expect(server.Close()).To(Succeed())
+		Eventually(tailing).Should(Receive(BeNil()))
+	})
+})
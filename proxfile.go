@@ -3,6 +3,7 @@ package prox
 import (
 	"io"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -17,6 +18,13 @@ type ProxfileProcess struct {
 	Script string
 	Env    []string
 
+	Timeout      time.Duration `yaml:"timeout"`
+	StartTimeout time.Duration `yaml:"start_timeout"`
+	Ready        string        `yaml:"ready"`
+
+	Schedule string        `yaml:"schedule"`
+	Overlap  OverlapPolicy `yaml:"overlap"`
+
 	Format string // e.g. json
 	Fields struct {
 		Message string
@@ -38,6 +46,13 @@ type proxfileProcess struct {
 	Script string
 	Env    []string
 
+	Timeout      time.Duration `yaml:"timeout"`
+	StartTimeout time.Duration `yaml:"start_timeout"`
+	Ready        string        `yaml:"ready"`
+
+	Schedule string        `yaml:"schedule"`
+	Overlap  OverlapPolicy `yaml:"overlap"`
+
 	Format string
 	Fields struct {
 		Message string
@@ -95,9 +110,14 @@ func ParseProxFile(reader io.Reader, env Environment) ([]Process, error) {
 		env.SetAll(pp.Env)
 
 		p := Process{
-			Name:   strings.TrimSpace(name),
-			Script: strings.TrimSpace(pp.Script),
-			Env:    env,
+			Name:         strings.TrimSpace(name),
+			Script:       strings.TrimSpace(pp.Script),
+			Env:          env,
+			Timeout:      pp.Timeout,
+			StartTimeout: pp.StartTimeout,
+			Ready:        pp.Ready,
+			Schedule:     strings.TrimSpace(pp.Schedule),
+			Overlap:      pp.Overlap,
 			Output: StructuredOutput{
 				Format:       pp.Format, // if empty the DefaultStructuredOutput will be applied automatically
 				MessageField: pp.Fields.Message,
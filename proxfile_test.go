@@ -2,6 +2,7 @@ package prox
 
 import (
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -116,4 +117,61 @@ processes:
 			}))
 		})
 	})
+
+	Describe("timeouts", func() {
+		content := `
+processes:
+  task:
+    script: "run-task"
+    timeout: 5m
+  service:
+    script: "serve"
+    start_timeout: 30s
+    ready: "/listening on/i"
+`
+
+		It("should parse the timeouts and ready condition", func() {
+			processes, err := ParseProxFile(strings.NewReader(content), Environment{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(processes).To(ContainElement(Process{
+				Name:    "task",
+				Script:  "run-task",
+				Env:     Environment{},
+				Output:  StructuredOutput{TagColors: map[string]string{}},
+				Timeout: 5 * time.Minute,
+			}))
+			Expect(processes).To(ContainElement(Process{
+				Name:         "service",
+				Script:       "serve",
+				Env:          Environment{},
+				Output:       StructuredOutput{TagColors: map[string]string{}},
+				StartTimeout: 30 * time.Second,
+				Ready:        "/listening on/i",
+			}))
+		})
+	})
+
+	Describe("schedules", func() {
+		content := `
+processes:
+  backup:
+    script: "make backup"
+    schedule: "*/15 * * * *"
+    overlap: queue
+`
+
+		It("should parse the schedule and overlap policy", func() {
+			processes, err := ParseProxFile(strings.NewReader(content), Environment{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(processes).To(Equal([]Process{{
+				Name:     "backup",
+				Script:   "make backup",
+				Env:      Environment{},
+				Output:   StructuredOutput{TagColors: map[string]string{}},
+				Schedule: "*/15 * * * *",
+				Overlap:  OverlapQueue,
+			}}))
+		})
+	})
 })
@@ -0,0 +1,212 @@
+package prox
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// A ReloadDiff describes how the configuration of the processes of an Executor
+// has changed when it was reloaded.
+type ReloadDiff struct {
+	Added   []string
+	Removed []string
+	Changed []ProcessChange
+}
+
+// A ProcessChange describes which parts of the configuration of a single
+// process have changed (e.g. "script", "env", "output" or "runner").
+type ProcessChange struct {
+	Name   string
+	Fields []string
+}
+
+// Empty returns true if the diff does not contain any changes.
+func (d ReloadDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns a human readable representation of the diff with one line
+// per added, removed or changed process.
+func (d ReloadDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+
+	var lines []string
+	for _, name := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s (added)", name))
+	}
+	for _, name := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s (removed)", name))
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s (changed %s)", c.Name, strings.Join(c.Fields, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// diffProcesses compares the current configuration of processes with a new
+// one.
+func diffProcesses(current map[string]Process, next []Process) ReloadDiff {
+	var diff ReloadDiff
+	seen := map[string]bool{}
+	for _, p := range next {
+		seen[p.Name] = true
+		old, ok := current[p.Name]
+		if !ok {
+			diff.Added = append(diff.Added, p.Name)
+			continue
+		}
+
+		if fields := changedFields(old, p); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, ProcessChange{Name: p.Name, Fields: fields})
+		}
+	}
+
+	for name := range current {
+		if !seen[name] {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Name < diff.Changed[j].Name
+	})
+
+	return diff
+}
+
+func changedFields(old, next Process) []string {
+	var fields []string
+	if old.Script != next.Script {
+		fields = append(fields, "script")
+	}
+	if !reflect.DeepEqual(old.Env, next.Env) {
+		fields = append(fields, "env")
+	}
+	if !reflect.DeepEqual(old.Output, next.Output) {
+		fields = append(fields, "output")
+	}
+	if old.Timeout != next.Timeout {
+		fields = append(fields, "timeout")
+	}
+	if old.StartTimeout != next.StartTimeout {
+		fields = append(fields, "start_timeout")
+	}
+	if old.Ready != next.Ready {
+		fields = append(fields, "ready")
+	}
+	if old.Schedule != next.Schedule {
+		fields = append(fields, "schedule")
+	}
+	if old.Overlap != next.Overlap {
+		fields = append(fields, "overlap")
+	}
+	if old.Runner != nil || next.Runner != nil {
+		// functions are never deeply equal so a RunnerFunc always counts as change
+		if !reflect.DeepEqual(old.Runner, next.Runner) {
+			fields = append(fields, "runner")
+		}
+	}
+
+	return fields
+}
+
+// Reload compares the given processes with the configuration of the currently
+// running processes and applies the difference. Added processes are started,
+// removed processes are stopped and processes whose configuration has changed
+// are restarted. All other processes keep running. Scheduled processes are
+// not started but scheduled again. The diff is printed via the prox output
+// before it is applied.
+func (e *Executor) Reload(processes []Process) (ReloadDiff, error) {
+	err := Validate(processes)
+	if err != nil {
+		return ReloadDiff{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.ctx == nil || e.done || e.ctx.Err() != nil {
+		return ReloadDiff{}, errors.New("executor is not running")
+	}
+
+	diff := diffProcesses(e.configs, processes)
+	e.printReloadDiff(diff)
+
+	for _, name := range diff.Removed {
+		e.stop(name)
+		e.unschedule(name)
+		delete(e.configs, name)
+		delete(e.outputs, name)
+		delete(e.marks, name)
+		if e.logFiles != nil {
+			e.logFiles.remove(name)
+		}
+	}
+
+	changed := map[string][]string{}
+	for _, c := range diff.Changed {
+		changed[c.Name] = c.Fields
+	}
+
+	for _, p := range processes {
+		fields, isChanged := changed[p.Name]
+		_, exists := e.configs[p.Name]
+		e.configs[p.Name] = p
+		if exists && !isChanged {
+			continue
+		}
+
+		if containsString(fields, "output") {
+			e.replaceFormattedOutput(p.Name)
+		}
+
+		delete(e.parsers, p.Name) // the output or environment might have changed
+		if e.logFiles != nil && exists {
+			e.logFiles.add(p) // new processes are added with their output
+		}
+
+		if p.Schedule != "" {
+			e.stop(p.Name)
+			e.schedule(p) // the schedule was validated already
+			continue
+		}
+
+		e.unschedule(p.Name)
+
+		np := e.newProcess(p, e.logger)
+		if _, running := e.running[p.Name]; running {
+			e.restarts[p.Name] = np
+			e.stop(p.Name)
+		} else {
+			e.start(np)
+		}
+	}
+
+	return diff, nil
+}
+
+// printReloadDiff prints the diff via the prox output. The caller must hold
+// e.mu.
+func (e *Executor) printReloadDiff(diff ReloadDiff) {
+	if e.proxOutput == nil {
+		e.logger.Info("Reloading configuration", zap.Stringer("diff", diff))
+		return
+	}
+
+	if diff.Empty() {
+		fmt.Fprintf(e.proxOutput, "Reloading configuration: %s\n", diff)
+		return
+	}
+
+	fmt.Fprintf(e.proxOutput, "Reloading configuration:\n%s\n", diff)
+}
@@ -0,0 +1,173 @@
+package prox
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ReloadDiff", func() {
+	It("should detect added, removed and changed processes", func() {
+		current := map[string]Process{
+			"unchanged": {Name: "unchanged", Script: "echo 1"},
+			"removed":   {Name: "removed", Script: "echo 2"},
+			"script":    {Name: "script", Script: "echo 3"},
+			"env":       {Name: "env", Script: "echo 4", Env: NewEnv([]string{"FOO=bar"})},
+			"output":    {Name: "output", Script: "echo 5"},
+		}
+
+		diff := diffProcesses(current, []Process{
+			{Name: "unchanged", Script: "echo 1"},
+			{Name: "script", Script: "echo three"},
+			{Name: "env", Script: "echo 4", Env: NewEnv([]string{"FOO=baz"})},
+			{Name: "output", Script: "echo 5", Output: StructuredOutput{Format: "json"}},
+			{Name: "added", Script: "echo 6"},
+		})
+
+		Expect(diff.Added).To(Equal([]string{"added"}))
+		Expect(diff.Removed).To(Equal([]string{"removed"}))
+		Expect(diff.Changed).To(Equal([]ProcessChange{
+			{Name: "env", Fields: []string{"env"}},
+			{Name: "output", Fields: []string{"output"}},
+			{Name: "script", Fields: []string{"script"}},
+		}))
+
+		Expect(diff.String()).To(Equal("+ added (added)\n" +
+			"- removed (removed)\n" +
+			"~ env (changed env)\n" +
+			"~ output (changed output)\n" +
+			"~ script (changed script)",
+		))
+	})
+
+	It("should be empty if nothing has changed", func() {
+		current := map[string]Process{"test": {Name: "test", Script: "echo 1"}}
+		diff := diffProcesses(current, []Process{{Name: "test", Script: "echo 1"}})
+		Expect(diff.Empty()).To(BeTrue())
+		Expect(diff.String()).To(Equal("no changes"))
+	})
+})
+
+var _ = Describe("Executor.Reload", func() {
+	var (
+		executor *TestExecutor
+		output   *Buffer
+		runner   *countingRunner
+		done     chan error
+		cancel   func()
+	)
+
+	BeforeEach(func() {
+		output = NewBuffer()
+		executor = TestNewExecutor(output)
+		executor.DisableColoredOutput()
+		runner = &countingRunner{starts: map[string]int{}}
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan error, 1)
+		go func() {
+			done <- executor.Executor.Run(ctx, []Process{
+				runner.process("p1", "FOO=1"),
+				runner.process("p2", "FOO=2"),
+				runner.process("p3", "FOO=3"),
+			})
+		}()
+
+		Eventually(runner.Running).Should(ConsistOf("p1", "p2", "p3"))
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(Receive())
+	})
+
+	It("should start added, stop removed and restart changed processes", func() {
+		diff, err := executor.Reload([]Process{
+			runner.process("p1", "FOO=1"),
+			runner.process("p2", "FOO=changed"),
+			runner.process("p4", "FOO=4"),
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Added).To(Equal([]string{"p4"}))
+		Expect(diff.Removed).To(Equal([]string{"p3"}))
+		Expect(diff.Changed).To(Equal([]ProcessChange{{Name: "p2", Fields: []string{"env"}}}))
+
+		Eventually(runner.Running).Should(ConsistOf("p1", "p2", "p4"))
+		Eventually(func() int { return runner.Starts("p2") }).Should(Equal(2))
+		Expect(runner.Starts("p1")).To(Equal(1), "unchanged processes should not be restarted")
+		Expect(executor.runningProcesses()).To(Equal([]string{"p1", "p2", "p4"}))
+
+		Expect(output).To(Say(`prox\s+│ Reloading configuration:`))
+		Expect(output).To(Say(`prox\s+│ \+ p4 \(added\)`))
+		Expect(output).To(Say(`prox\s+│ - p3 \(removed\)`))
+		Expect(output).To(Say(`prox\s+│ ~ p2 \(changed env\)`))
+		Consistently(done).ShouldNot(Receive(), "the executor should keep running")
+	})
+
+	It("should not apply an invalid configuration", func() {
+		_, err := executor.Reload([]Process{{Name: "p1"}})
+		Expect(err).To(MatchError(`process "p1": missing script`))
+		Consistently(runner.Running).Should(ConsistOf("p1", "p2", "p3"))
+	})
+})
+
+// countingRunner creates Runners which block until they are interrupted and
+// keeps track of how often each process was started.
+type countingRunner struct {
+	mu      sync.Mutex
+	starts  map[string]int
+	running []string
+}
+
+// runnerConfig is used as Runner of a Process to make it comparable when the
+// configuration is reloaded.
+type runnerConfig struct {
+	name   string
+	runner *countingRunner
+}
+
+func (r *countingRunner) process(name string, env ...string) Process {
+	return Process{
+		Name:   name,
+		Env:    NewEnv(env),
+		Runner: &runnerConfig{name: name, runner: r},
+	}
+}
+
+func (c *runnerConfig) Run(ctx context.Context, _ io.Writer) error {
+	c.runner.mu.Lock()
+	c.runner.starts[c.name]++
+	c.runner.running = append(c.runner.running, c.name)
+	c.runner.mu.Unlock()
+
+	<-ctx.Done()
+
+	c.runner.mu.Lock()
+	for i, n := range c.runner.running {
+		if n == c.name {
+			c.runner.running = append(c.runner.running[:i], c.runner.running[i+1:]...)
+			break
+		}
+	}
+	c.runner.mu.Unlock()
+
+	return ctx.Err()
+}
+
+func (r *countingRunner) Running() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.running...)
+}
+
+func (r *countingRunner) Starts(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.starts[name]
+}
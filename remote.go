@@ -0,0 +1,404 @@
+package prox
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fgrosse/prox/proxpb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// The files in the directory that is passed to Server.SetRemoteAddress. They
+// are created when the Server is started for the first time.
+const (
+	RemoteCertFile  = "cert.pem"  // the self-signed TLS certificate of the Server
+	RemoteKeyFile   = "key.pem"   // the private key of the certificate
+	RemoteTokenFile = "token.txt" // the bearer token that clients must send
+)
+
+// remoteCertValidity is the time in which a generated certificate is valid.
+const remoteCertValidity = 10 * 365 * 24 * time.Hour
+
+// SetRemoteAddress makes the Server serve its gRPC service not only on the
+// unix socket but also on the given TCP address (e.g. ":7777") so it can be
+// controlled from other machines via NewRemoteClient. Connections are
+// encrypted via TLS and every request must contain the bearer token of the
+// Server. A self-signed certificate and a random token are created in the
+// given directory when the Server is started for the first time (see
+// RemoteTokenFile). It must be called before Server.Run(…).
+func (s *Server) SetRemoteAddress(addr, dir string) {
+	s.remoteAddr = addr
+	s.remoteDir = dir
+}
+
+// RemoteAddr returns the TCP address of the gRPC service for remote clients or
+// nil if the Server does not listen on TCP (yet).
+func (s *Server) RemoteAddr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.remoteListener == nil {
+		return nil
+	}
+
+	return s.remoteListener.Addr()
+}
+
+// listenRemote loads or creates the certificate and token of the Server and
+// opens the TCP listener for remote clients.
+func (s *Server) listenRemote() error {
+	err := os.MkdirAll(s.remoteDir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory for remote credentials")
+	}
+
+	cert, err := loadOrCreateCertificate(s.remoteDir)
+	if err != nil {
+		return err
+	}
+
+	s.remoteToken, err = loadOrCreateToken(filepath.Join(s.remoteDir, RemoteTokenFile))
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", s.remoteAddr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.remoteListener = l
+	s.mu.Unlock()
+
+	s.remoteCert = cert
+	s.logger.Info("Listening for remote clients",
+		zap.Stringer("address", l.Addr()),
+		zap.String("token_file", filepath.Join(s.remoteDir, RemoteTokenFile)),
+		zap.String("certificate_sha256", certFingerprint(cert.Certificate[0])),
+	)
+
+	return nil
+}
+
+// serveRemote starts a second gRPC server for the remote listener which only
+// accepts TLS connections and requests with the bearer token of the Server.
+func (s *Server) serveRemote(service proxpb.ProxServer) {
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{s.remoteCert},
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	s.remoteGRPC = grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+			return s.unaryInterceptor(ctx, req, info, handler)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authorize(stream.Context()); err != nil {
+				return err
+			}
+			return s.streamInterceptor(srv, stream, info, handler)
+		}),
+	)
+
+	proxpb.RegisterProxServer(s.remoteGRPC, service)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := s.remoteGRPC.Serve(s.remoteListener)
+		if err != nil && err != grpc.ErrServerStopped && !isClosedConnectionError(err) {
+			s.logger.Error("Failed to serve gRPC for remote clients", zap.Error(err))
+		}
+	}()
+}
+
+// authorize checks the bearer token of a request of a remote client.
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.remoteToken)) == 1 {
+			return nil
+		}
+	}
+
+	s.logger.Warn("Rejected request of remote client with invalid token")
+	return grpcError(serverErrorf(CodeUnauthorized, "invalid or missing token"))
+}
+
+// loadOrCreateCertificate loads the certificate of the Server from the
+// directory or creates a new self-signed certificate if there is none yet.
+func loadOrCreateCertificate(dir string) (tls.Certificate, error) {
+	certFile := filepath.Join(dir, RemoteCertFile)
+	keyFile := filepath.Join(dir, RemoteKeyFile)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil {
+		return cert, nil
+	}
+	if !os.IsNotExist(errors.Cause(err)) {
+		return tls.Certificate{}, errors.Wrap(err, "failed to load TLS certificate")
+	}
+
+	certPEM, keyPEM, err := generateCertificate()
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "failed to generate TLS certificate")
+	}
+
+	err = ioutil.WriteFile(keyFile, keyPEM, 0600)
+	if err == nil {
+		err = ioutil.WriteFile(certFile, certPEM, 0600)
+	}
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "failed to write TLS certificate")
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// generateCertificate creates a self-signed certificate for the local host.
+func generateCertificate() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"prox"}, CommonName: hostname},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(remoteCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname != "" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// loadOrCreateToken reads the bearer token from the file or writes a new
+// random token to it if it does not exist yet.
+func loadOrCreateToken(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return "", errors.Errorf("token file %s is empty", path)
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", errors.Wrap(err, "failed to read token file")
+	}
+
+	random := make([]byte, 32)
+	_, err = rand.Read(random)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate token")
+	}
+
+	token := hex.EncodeToString(random)
+	err = ioutil.WriteFile(path, []byte(token+"\n"), 0600)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to write token file")
+	}
+
+	return token, nil
+}
+
+// certFingerprint returns the SHA-256 fingerprint of a DER encoded certificate.
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// RemoteOptions configure the connection of NewRemoteClient. The certificate
+// of the Server is always verified so either CertFile or KnownHostsFile must
+// be set.
+type RemoteOptions struct {
+	// Token is the bearer token of the Server (see RemoteTokenFile).
+	Token string
+
+	// CertFile is the path to a copy of the certificate of the Server (see
+	// RemoteCertFile). If it is set, the client only connects to a Server with
+	// exactly this certificate.
+	CertFile string
+
+	// KnownHostsFile is the path to a file with the certificate fingerprints of
+	// known Servers. It is used if CertFile is empty. The fingerprint of a
+	// Server is added to the file on the first connection (trust on first use)
+	// and the client refuses to connect if the certificate changes afterwards.
+	KnownHostsFile string
+}
+
+// NewRemoteClient creates a new prox Client and immediately connects it to a
+// prox Server via TCP (see Server.SetRemoteAddress). It is the callers
+// responsibility to eventually close the client to release the connection.
+func NewRemoteClient(addr string, opts RemoteOptions, debug bool) (*Client, error) {
+	if opts.Token == "" {
+		return nil, errors.New("a token is required to connect to a remote prox server")
+	}
+
+	var verify func(der []byte) error
+	switch {
+	case opts.CertFile != "":
+		pinned, err := readCertificate(opts.CertFile)
+		if err != nil {
+			return nil, err
+		}
+
+		verify = func(der []byte) error {
+			if !bytes.Equal(der, pinned) {
+				return certificateMismatchError(opts.CertFile)
+			}
+			return nil
+		}
+	case opts.KnownHostsFile != "":
+		verify = func(der []byte) error {
+			return verifyKnownHost(opts.KnownHostsFile, addr, certFingerprint(der))
+		}
+	default:
+		return nil, errors.New("a certificate or a known hosts file is required to connect to a remote prox server")
+	}
+
+	conf := &tls.Config{
+		// the certificate is self-signed so the chain cannot be verified and
+		// the certificate is pinned via VerifyPeerCertificate instead
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+		VerifyPeerCertificate: func(certs [][]byte, _ [][]*x509.Certificate) error {
+			if len(certs) == 0 {
+				return errors.New("the server did not send a certificate")
+			}
+			return verify(certs[0])
+		},
+	}
+
+	return dialClient(addr, NewLogger(os.Stderr, debug),
+		grpc.WithTransportCredentials(credentials.NewTLS(conf)),
+		grpc.WithPerRPCCredentials(tokenCredentials(opts.Token)),
+	)
+}
+
+// verifyKnownHost checks the fingerprint of the certificate of the Server at
+// addr against the known hosts file. Unknown hosts are added to the file.
+func verifyKnownHost(path, addr, fingerprint string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to read known hosts")
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != addr {
+			continue
+		}
+		if fields[1] != fingerprint {
+			return certificateMismatchError(fmt.Sprintf("the fingerprint of %s in %s", addr, path))
+		}
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory for known hosts")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open known hosts")
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s %s\n", addr, fingerprint)
+	return errors.Wrap(err, "failed to add host to known hosts")
+}
+
+// readCertificate returns the DER encoded certificate of a PEM file.
+func readCertificate(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read certificate")
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.Errorf("%s does not contain a PEM encoded certificate", path)
+	}
+
+	return block.Bytes, nil
+}
+
+// A certificateMismatchError is returned if the certificate of the server does
+// not match the certificate the client expects.
+type certificateMismatchError string
+
+func (e certificateMismatchError) Error() string {
+	return fmt.Sprintf("the certificate of the server does not match %s", string(e))
+}
+
+// Temporary tells gRPC to not try again.
+func (e certificateMismatchError) Temporary() bool {
+	return false
+}
+
+// tokenCredentials sends a bearer token with every request.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": fmt.Sprint("Bearer ", string(t))}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,149 @@
+package prox
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Remote clients", func() {
+	var (
+		dir    string
+		server *Server
+		runner *countingRunner
+		cancel context.CancelFunc
+		done   chan error
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "prox")
+		Expect(err).NotTo(HaveOccurred())
+
+		server = NewExecutorServer(filepath.Join(dir, "prox.sock"), true)
+		server.Executor.output = GinkgoWriter
+		server.SetRemoteAddress("localhost:0", filepath.Join(dir, "remote"))
+
+		runner = &countingRunner{starts: map[string]int{}}
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan error, 1)
+		go func() {
+			done <- server.Run(ctx, []Process{runner.process("p1")})
+		}()
+
+		Eventually(server.RemoteAddr).ShouldNot(BeNil())
+		Eventually(runner.Running).Should(ConsistOf("p1"))
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(Receive())
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	token := func() string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "remote", RemoteTokenFile))
+		Expect(err).NotTo(HaveOccurred())
+		return strings.TrimSpace(string(b))
+	}
+
+	It("should create the certificate and the token on the first run", func() {
+		for _, name := range []string{RemoteCertFile, RemoteKeyFile, RemoteTokenFile} {
+			info, err := os.Stat(filepath.Join(dir, "remote", name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		}
+
+		Expect(token()).To(HaveLen(64))
+
+		leaf, err := x509.ParseCertificate(server.remoteCert.Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaf.IsCA).To(BeFalse())
+		Expect(leaf.KeyUsage & x509.KeyUsageCertSign).To(BeZero())
+
+		cert, err := loadOrCreateCertificate(filepath.Join(dir, "remote"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cert.Certificate).To(Equal(server.remoteCert.Certificate), "the certificate should be reused")
+
+		t, err := loadOrCreateToken(filepath.Join(dir, "remote", RemoteTokenFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t).To(Equal(token()), "the token should be reused")
+	})
+
+	It("should serve clients with the token via TLS", func() {
+		opts := RemoteOptions{Token: token(), CertFile: filepath.Join(dir, "remote", RemoteCertFile)}
+		client, err := NewRemoteClient(server.RemoteAddr().String(), opts, false)
+		Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		infos, err := client.List(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(infos).To(HaveLen(1))
+		Expect(infos[0].Name).To(Equal("p1"))
+
+		Expect(client.RestartProcesses(context.Background(), "p1")).To(Equal([]string{"p1"}))
+		Eventually(func() int { return runner.Starts("p1") }).Should(Equal(2))
+	})
+
+	It("should reject requests with an invalid token", func() {
+		opts := RemoteOptions{Token: "secret", CertFile: filepath.Join(dir, "remote", RemoteCertFile)}
+		client, err := NewRemoteClient(server.RemoteAddr().String(), opts, false)
+		Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		_, err = client.List(context.Background())
+		Expect(err).To(Equal(&ServerError{Code: CodeUnauthorized, Message: "invalid or missing token"}))
+
+		_, err = client.StopProcesses(context.Background(), "p1")
+		Expect(err).To(Equal(&ServerError{Code: CodeUnauthorized, Message: "invalid or missing token"}))
+		Expect(runner.Running()).To(ConsistOf("p1"))
+
+		_, err = NewRemoteClient(server.RemoteAddr().String(), RemoteOptions{}, false)
+		Expect(err).To(MatchError("a token is required to connect to a remote prox server"))
+
+		_, err = NewRemoteClient(server.RemoteAddr().String(), RemoteOptions{Token: token()}, false)
+		Expect(err).To(MatchError("a certificate or a known hosts file is required to connect to a remote prox server"))
+	})
+
+	It("should trust the certificate of the server on the first connection", func() {
+		addr := server.RemoteAddr().String()
+		knownHosts := filepath.Join(dir, "client", "known_hosts")
+
+		client, err := NewRemoteClient(addr, RemoteOptions{Token: token(), KnownHostsFile: knownHosts}, false)
+		Expect(err).NotTo(HaveOccurred())
+		client.Close()
+
+		b, err := ioutil.ReadFile(knownHosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(addr + " " + certFingerprint(server.remoteCert.Certificate[0]) + "\n"))
+
+		client, err = NewRemoteClient(addr, RemoteOptions{Token: token(), KnownHostsFile: knownHosts}, false)
+		Expect(err).NotTo(HaveOccurred())
+		client.Close()
+
+		Expect(ioutil.WriteFile(knownHosts, []byte(addr+" 0123\n"), 0600)).To(Succeed())
+		_, err = NewRemoteClient(addr, RemoteOptions{Token: token(), KnownHostsFile: knownHosts}, false)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("the certificate of the server does not match the fingerprint of " + addr))
+	})
+
+	It("should not connect to a server with another certificate", func() {
+		certPEM, _, err := generateCertificate()
+		Expect(err).NotTo(HaveOccurred())
+
+		other := filepath.Join(dir, "other.pem")
+		Expect(ioutil.WriteFile(other, certPEM, 0600)).To(Succeed())
+
+		_, err = NewRemoteClient(server.RemoteAddr().String(), RemoteOptions{Token: token(), CertFile: other}, false)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("the certificate of the server does not match " + other))
+	})
+})
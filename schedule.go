@@ -0,0 +1,372 @@
+package prox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// A Schedule determines when a scheduled process is started.
+type Schedule interface {
+	// Next returns the first time after t at which the process should be
+	// started. If there is no such time, the zero time is returned.
+	Next(t time.Time) time.Time
+}
+
+// An OverlapPolicy determines what happens if a scheduled process should be
+// started while its previous run has not yet finished.
+type OverlapPolicy string
+
+// All supported overlap policies.
+const (
+	OverlapSkip  OverlapPolicy = "skip"  // do not start the process (default)
+	OverlapQueue OverlapPolicy = "queue" // start the process once the previous run has finished
+)
+
+// ParseSchedule parses the schedule of a process. It is either a cron
+// expression with five fields (minute, hour, day of month, month and day of
+// week) like "*/15 9-17 * * MON-FRI", one of the predefined schedules
+// "@yearly", "@monthly", "@weekly", "@daily" and "@hourly" or an interval
+// like "@every 5m" or simply "5m".
+func ParseSchedule(s string) (Schedule, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "@yearly", "@annually":
+		s = "0 0 1 1 *"
+	case "@monthly":
+		s = "0 0 1 * *"
+	case "@weekly":
+		s = "0 0 * * 0"
+	case "@daily", "@midnight":
+		s = "0 0 * * *"
+	case "@hourly":
+		s = "0 * * * *"
+	}
+
+	if strings.HasPrefix(s, "@every ") || !strings.Contains(s, " ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(s, "@every ")))
+		if err != nil {
+			return nil, errors.Errorf("invalid schedule %q: expected cron expression or interval", s)
+		}
+		if d <= 0 {
+			return nil, errors.Errorf("invalid schedule %q: interval must be positive", s)
+		}
+		return intervalSchedule(d), nil
+	}
+
+	return parseCronSchedule(s)
+}
+
+// intervalSchedule starts a process after a fixed interval.
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// cronSchedule starts a process at the times given by a cron expression. Each
+// field is a bit set in which bit n is set if the value n matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// If either the day of month or the day of week is unrestricted (i.e. it
+	// starts with a "*") both fields must match. Otherwise it is sufficient if
+	// one of them matches (see crontab(5)).
+	anyDay bool
+}
+
+var (
+	monthNames = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	dayNames = map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}
+)
+
+func parseCronSchedule(s string) (Schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("invalid schedule %q: expected 5 fields but got %d", s, len(fields))
+	}
+
+	var (
+		cs  cronSchedule
+		err error
+	)
+
+	parse := func(name, expr string, min, max int, names map[string]int) uint64 {
+		if err != nil {
+			return 0
+		}
+
+		var bits uint64
+		bits, err = parseCronField(expr, min, max, names)
+		err = errors.Wrapf(err, "invalid %s in schedule %q", name, s)
+		return bits
+	}
+
+	cs.minute = parse("minute", fields[0], 0, 59, nil)
+	cs.hour = parse("hour", fields[1], 0, 23, nil)
+	cs.dom = parse("day of month", fields[2], 1, 31, nil)
+	cs.month = parse("month", fields[3], 1, 12, monthNames)
+	cs.dow = parse("day of week", fields[4], 0, 7, dayNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sunday is either 0 or 7
+	if cs.dow&(1<<7) != 0 {
+		cs.dow |= 1
+	}
+
+	cs.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+	return cs, nil
+}
+
+// parseCronField parses a single field of a cron expression which is a comma
+// separated list of values, ranges ("1-5") or wildcards ("*"), each with an
+// optional step ("*/15").
+func parseCronField(expr string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		step, hasStep := 1, false
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.Errorf("invalid step %q", part[i+1:])
+			}
+			step, hasStep = n, true
+			part = part[:i]
+		}
+
+		var lo, hi int
+		switch i := strings.Index(part, "-"); {
+		case part == "*":
+			lo, hi = min, max
+		case i >= 0:
+			var err error
+			if lo, err = parseCronValue(part[:i], names); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(part[i+1:], names); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if lo, err = parseCronValue(part, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if hasStep {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, errors.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToUpper(s)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", s)
+	}
+
+	return n, nil
+}
+
+// Next returns the next minute after t that matches the cron expression.
+func (s cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Give up eventually if the expression can never match (e.g. "0 0 30 2 *").
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// A scheduledProcess contains the state of a process that is started
+// periodically by the Executor. All fields are guarded by the mutex of the
+// Executor.
+type scheduledProcess struct {
+	schedule Schedule
+	policy   OverlapPolicy
+	timer    *time.Timer
+	next     time.Time // the next time the process will be started
+	runs     int       // how often the process was started
+	queued   bool      // whether a run was queued because the process was still running
+}
+
+// newScheduledProcess creates the schedule of a process. It does not start
+// any timer until it is passed to Executor.scheduleNext(…).
+func newScheduledProcess(p Process) (*scheduledProcess, error) {
+	s, err := ParseSchedule(p.Schedule)
+	if err != nil {
+		return nil, errors.Wrapf(err, "process %q", p.Name)
+	}
+
+	policy := p.Overlap
+	if policy == "" {
+		policy = OverlapSkip
+	}
+
+	return &scheduledProcess{schedule: s, policy: policy}, nil
+}
+
+// schedule starts to run a process according to its schedule. If the process
+// was scheduled already, its previous schedule is replaced but the run counter
+// is kept. The caller must hold e.mu.
+func (e *Executor) schedule(p Process) error {
+	sp, err := newScheduledProcess(p)
+	if err != nil {
+		return err
+	}
+
+	if old, ok := e.schedules[p.Name]; ok {
+		sp.runs = old.runs
+		e.unschedule(p.Name)
+	}
+
+	e.processOutput(e.out, p) // make the process available for tailing right away
+	e.schedules[p.Name] = sp
+	e.scheduleNext(p.Name, sp)
+	return nil
+}
+
+// unschedule stops to run a process according to its schedule. A run that is
+// currently active is not stopped. The caller must hold e.mu.
+func (e *Executor) unschedule(name string) {
+	sp, ok := e.schedules[name]
+	if !ok {
+		return
+	}
+
+	if sp.timer != nil {
+		sp.timer.Stop()
+	}
+
+	delete(e.schedules, name)
+}
+
+// stopSchedules stops the timers of all scheduled processes. The caller must
+// hold e.mu.
+func (e *Executor) stopSchedules() {
+	for _, sp := range e.schedules {
+		if sp.timer != nil {
+			sp.timer.Stop()
+		}
+	}
+}
+
+// scheduleNext sets the timer to start the next run of a scheduled process.
+// The caller must hold e.mu.
+func (e *Executor) scheduleNext(name string, sp *scheduledProcess) {
+	now := time.Now()
+	sp.next = sp.schedule.Next(now)
+	if sp.next.IsZero() {
+		e.logger.Warn("Scheduled process will never run again", zap.String("process_name", name))
+		return
+	}
+
+	e.logger.Debug("Scheduled next run of process",
+		zap.String("process_name", name),
+		zap.Time("next_run", sp.next),
+	)
+
+	sp.timer = time.AfterFunc(sp.next.Sub(now), func() {
+		e.trigger(name, sp)
+	})
+}
+
+// trigger is called when a scheduled process is due. Depending on the overlap
+// policy of the process it is either started, skipped or queued if its
+// previous run is still active.
+func (e *Executor) trigger(name string, sp *scheduledProcess) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.schedules[name] != sp || e.done || e.ctx.Err() != nil {
+		// the process was unscheduled or the Executor is shutting down
+		return
+	}
+
+	e.scheduleNext(name, sp)
+
+	if _, running := e.running[name]; !running {
+		e.start(e.scheduledRun(name, sp))
+		return
+	}
+
+	switch {
+	case sp.policy == OverlapQueue && !sp.queued:
+		e.logger.Info("Queueing scheduled run because previous run is still active", zap.String("process_name", name))
+		sp.queued = true
+	case sp.policy == OverlapQueue:
+		e.logger.Info("Skipping scheduled run because a run is queued already", zap.String("process_name", name))
+	default:
+		e.logger.Info("Skipping scheduled run because previous run is still active", zap.String("process_name", name))
+	}
+}
+
+// scheduledRun creates a new instance of a scheduled process for its next run.
+// The caller must hold e.mu.
+func (e *Executor) scheduledRun(name string, sp *scheduledProcess) process {
+	sp.runs++
+	e.printScheduledRun(name, sp)
+	return e.newProcess(e.configs[name], e.logger)
+}
+
+// printScheduledRun prints the run counter of a scheduled process via the prox
+// output. The caller must hold e.mu.
+func (e *Executor) printScheduledRun(name string, sp *scheduledProcess) {
+	if e.proxOutput == nil {
+		return // the run is logged when the process is started
+	}
+
+	fmt.Fprintf(e.proxOutput, "Starting run #%d of %s (next run at %s)\n",
+		sp.runs, name, sp.next.Format("15:04:05"),
+	)
+}
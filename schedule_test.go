@@ -0,0 +1,176 @@
+package prox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ParseSchedule", func() {
+	// 2018-12-09 was a Sunday
+	now := time.Date(2018, 12, 9, 13, 37, 42, 0, time.UTC)
+
+	DescribeTable("next run",
+		func(schedule string, expected time.Time) {
+			s, err := ParseSchedule(schedule)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Next(now)).To(Equal(expected))
+		},
+
+		Entry("interval", "@every 90s", now.Add(90*time.Second)),
+		Entry("plain interval", "5m", now.Add(5*time.Minute)),
+		Entry("every minute", "* * * * *", time.Date(2018, 12, 9, 13, 38, 0, 0, time.UTC)),
+		Entry("step", "*/15 * * * *", time.Date(2018, 12, 9, 13, 45, 0, 0, time.UTC)),
+		Entry("list", "5,40 * * * *", time.Date(2018, 12, 9, 13, 40, 0, 0, time.UTC)),
+		Entry("range", "0 9-17 * * *", time.Date(2018, 12, 9, 14, 0, 0, 0, time.UTC)),
+		Entry("hourly", "@hourly", time.Date(2018, 12, 9, 14, 0, 0, 0, time.UTC)),
+		Entry("daily", "@daily", time.Date(2018, 12, 10, 0, 0, 0, 0, time.UTC)),
+		Entry("weekly", "@weekly", time.Date(2018, 12, 16, 0, 0, 0, 0, time.UTC)),
+		Entry("monthly", "@monthly", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("day names", "30 8 * * MON-FRI", time.Date(2018, 12, 10, 8, 30, 0, 0, time.UTC)),
+		Entry("sunday as 7", "0 20 * * 7", time.Date(2018, 12, 9, 20, 0, 0, 0, time.UTC)),
+		Entry("month names", "0 0 1 mar *", time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("day of month or day of week", "0 0 15 * MON", time.Date(2018, 12, 10, 0, 0, 0, 0, time.UTC)),
+		Entry("impossible date", "0 0 30 2 *", time.Time{}),
+	)
+
+	DescribeTable("invalid schedules",
+		func(schedule, expectedErr string) {
+			_, err := ParseSchedule(schedule)
+			Expect(err).To(MatchError(expectedErr))
+		},
+
+		Entry("garbage", "sometimes", `invalid schedule "sometimes": expected cron expression or interval`),
+		Entry("negative interval", "@every -5m", `invalid schedule "@every -5m": interval must be positive`),
+		Entry("missing fields", "* * *", `invalid schedule "* * *": expected 5 fields but got 3`),
+		Entry("out of range", "60 * * * *", `invalid minute in schedule "60 * * * *": "60" is out of range 0-59`),
+		Entry("invalid step", "*/0 * * * *", `invalid minute in schedule "*/0 * * * *": invalid step "0"`),
+		Entry("invalid name", "* * * * FOO", `invalid day of week in schedule "* * * * FOO": invalid value "FOO"`),
+	)
+})
+
+var _ = Describe("Scheduled processes", func() {
+	var (
+		executor *TestExecutor
+		output   *Buffer
+		cancel   func()
+		done     chan error
+	)
+
+	BeforeEach(func() {
+		output = NewBuffer()
+		executor = TestNewExecutor(output)
+		executor.DisableColoredOutput()
+		done = make(chan error, 1)
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(Receive())
+	})
+
+	run := func(pp ...Process) {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			done <- executor.Executor.Run(ctx, pp)
+		}()
+	}
+
+	It("should start a process periodically and count its runs", func() {
+		runs := make(chan bool, 10)
+		run(Process{
+			Name:     "job",
+			Schedule: "@every 50ms",
+			Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+				runs <- true
+				return nil
+			}),
+		})
+
+		Eventually(runs).Should(Receive())
+		Eventually(runs).Should(Receive())
+		Expect(output).To(Say(`Starting process\s+{"process_name":"job","run":1}`))
+		Expect(output).To(Say(`Starting process\s+{"process_name":"job","run":2}`))
+
+		info := executor.Info("job")
+		Expect(info.Runs).To(BeNumerically(">=", 2))
+		Expect(info.NextRun).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
+		Expect(executor.listedProcesses()).To(Equal([]string{"job"}))
+		Consistently(done).ShouldNot(Receive(), "the executor should keep running")
+	})
+
+	It("should not stop the other processes if a scheduled run fails", func() {
+		run(
+			Process{
+				Name:     "job",
+				Schedule: "@every 20ms",
+				Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+					return errors.New("something went wrong")
+				}),
+			},
+			Process{
+				Name: "service",
+				Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}),
+			},
+		)
+
+		Eventually(output).Should(Say(`Process error\s+{"process_name":"job","error":"something went wrong"}`))
+		Consistently(done).ShouldNot(Receive())
+		Expect(executor.runningProcesses()).To(ContainElement("service"))
+	})
+
+	It("should skip a run if the previous run is still active", func() {
+		starts := make(chan bool, 10)
+		run(Process{
+			Name:     "job",
+			Schedule: "@every 20ms",
+			Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+				starts <- true
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+		})
+
+		Eventually(starts).Should(Receive())
+		Eventually(output).Should(Say("Skipping scheduled run because previous run is still active"))
+		Eventually(output).Should(Say("Skipping scheduled run because previous run is still active"))
+		Expect(starts).NotTo(Receive())
+	})
+
+	It("should start a queued run once the previous run has finished", func() {
+		release := make(chan bool)
+		starts := make(chan int, 10)
+		var n int
+		run(Process{
+			Name:     "job",
+			Schedule: "@every 30ms",
+			Overlap:  OverlapQueue,
+			Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+				n++
+				starts <- n
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+				return nil
+			}),
+		})
+
+		Eventually(starts).Should(Receive(Equal(1)))
+		Eventually(output).Should(Say("Queueing scheduled run because previous run is still active"))
+		Eventually(output).Should(Say("Skipping scheduled run because a run is queued already"))
+
+		release <- true
+		Eventually(starts).Should(Receive(Equal(2)), "the queued run should start immediately")
+	})
+})
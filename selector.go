@@ -0,0 +1,108 @@
+package prox
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AllProcesses is the pattern that selects every process.
+const AllProcesses = "*"
+
+// A processSelector selects processes by a list of patterns. Each pattern is
+// either the exact name of a process, a glob pattern like "api-*" (see
+// path.Match) or an exclusion of one of the former like "!worker". If there
+// are only exclusions, they are applied to all processes.
+type processSelector struct {
+	patterns []string
+	include  []string
+	exclude  []string
+}
+
+// newProcessSelector validates the patterns and creates a processSelector.
+func newProcessSelector(patterns []string) (*processSelector, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("no processes given")
+	}
+
+	s := &processSelector{patterns: patterns}
+	for _, p := range patterns {
+		exclude := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+
+		if _, err := path.Match(p, ""); p == "" || err != nil {
+			return nil, errors.Errorf("invalid process pattern %q", p)
+		}
+
+		if exclude {
+			s.exclude = append(s.exclude, p)
+		} else {
+			s.include = append(s.include, p)
+		}
+	}
+
+	if len(s.include) == 0 {
+		s.include = []string{AllProcesses}
+	}
+
+	return s, nil
+}
+
+// match returns true if the process with the given name is selected.
+func (s *processSelector) match(name string) bool {
+	return matchAny(s.include, name) && !matchAny(s.exclude, name)
+}
+
+// resolve returns the sorted names of all selected processes. Each pattern
+// must match at least one of the given names and at least one process must
+// remain after the exclusions.
+func (s *processSelector) resolve(names []string) ([]string, error) {
+	for _, patterns := range [][]string{s.include, s.exclude} {
+		for _, p := range patterns {
+			if matchAny([]string{p}, names...) {
+				continue
+			}
+
+			if isGlob(p) {
+				return nil, serverErrorf(CodeNotFound, "no process matches %q", p)
+			}
+
+			return nil, noSuchProcessError(p)
+		}
+	}
+
+	var selected []string
+	for _, name := range names {
+		if s.match(name) {
+			selected = append(selected, name)
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, serverErrorf(CodeNotFound, "all processes are excluded by %q", strings.Join(s.patterns, " "))
+	}
+
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// matchAny returns true if any of the patterns matches any of the names.
+func matchAny(patterns []string, names ...string) bool {
+	for _, p := range patterns {
+		for _, name := range names {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isGlob returns true if the pattern contains any special characters of
+// path.Match.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
@@ -0,0 +1,54 @@
+package prox
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("processSelector", func() {
+	names := []string{"api-1", "api-2", "db", "worker"}
+
+	DescribeTable("selecting processes",
+		func(patterns []string, expected []string) {
+			s, err := newProcessSelector(patterns)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.resolve(names)).To(Equal(expected))
+		},
+
+		Entry("name", []string{"db"}, []string{"db"}),
+		Entry("names", []string{"worker", "db"}, []string{"db", "worker"}),
+		Entry("glob", []string{"api-*"}, []string{"api-1", "api-2"}),
+		Entry("character class", []string{"api-[2-9]"}, []string{"api-2"}),
+		Entry("all", []string{AllProcesses}, names),
+		Entry("all with exclusion", []string{AllProcesses, "!worker"}, []string{"api-1", "api-2", "db"}),
+		Entry("only exclusions", []string{"!api-*", "!db"}, []string{"worker"}),
+		Entry("overlapping patterns", []string{"api-*", "api-1"}, []string{"api-1", "api-2"}),
+	)
+
+	DescribeTable("invalid patterns",
+		func(patterns []string, expectedErr string) {
+			_, err := newProcessSelector(patterns)
+			Expect(err).To(MatchError(expectedErr))
+		},
+
+		Entry("no patterns", nil, "no processes given"),
+		Entry("empty", []string{""}, `invalid process pattern ""`),
+		Entry("empty exclusion", []string{"!"}, `invalid process pattern ""`),
+		Entry("malformed glob", []string{"api-["}, `invalid process pattern "api-["`),
+	)
+
+	DescribeTable("patterns without matches",
+		func(patterns []string, expectedErr string) {
+			s, err := newProcessSelector(patterns)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s.resolve(names)
+			Expect(err).To(MatchError(expectedErr))
+		},
+
+		Entry("unknown name", []string{"db", "cache"}, `no such process "cache"`),
+		Entry("glob", []string{"cache-*"}, `no process matches "cache-*"`),
+		Entry("exclusion", []string{AllProcesses, "!cache"}, `no such process "cache"`),
+		Entry("everything excluded", []string{"db", "!d?"}, `all processes are excluded by "db !d?"`),
+	)
+})
@@ -1,30 +1,50 @@
 package prox
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"io"
 	"net"
-	"sort"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // A Server wraps an Executor to expose its functionality via a unix socket.
+// Clients can either use the gRPC service (see Client and proxpb/prox.proto)
+// or the HTTP API on the same socket.
 type Server struct {
 	*Executor
 	socketPath string
 	listener   net.Listener
+	lock       *instanceLock
 	logger     *zap.Logger
-}
-
-// socketMessage is the underlying message type that is passed between a prox
-// Server and Client.
-type socketMessage struct {
-	Command string
-	Args    []string
+	load       func() ([]Process, error)
+	shutdown   func() // stops the Executor gracefully
+
+	httpAddr     string        // optional TCP address of the HTTP API
+	httpListener net.Listener  // listener of httpAddr
+	httpConns    *connListener // HTTP connections of the unix socket
+	http         *http.Server
+	grpcConns    *connListener // gRPC connections of the unix socket
+	grpc         *grpc.Server
+
+	remoteAddr     string       // optional TCP address of the gRPC service for remote clients
+	remoteDir      string       // directory of the certificate and token for remote clients
+	remoteListener net.Listener // listener of remoteAddr
+	remoteCert     tls.Certificate
+	remoteToken    string
+	remoteGRPC     *grpc.Server
+
+	mu          sync.Mutex
+	stopServing func()         // stops accepting connections and closes all open connections
+	wg          sync.WaitGroup // waits for the accept loop and all open connections
 }
 
 // NewExecutorServer creates a new Server. This function does not start the
@@ -40,23 +60,82 @@ func NewExecutorServer(socketPath string, debug bool) *Server {
 // Run opens a unix socket using the path that was passed via NewExecutor and
 // then starts the Executor. It is the callers responsibility to eventually call
 // Server.Close() in order to close the unix socket connect.
+//
+// Only a single Server can use the same socket path at a time. This is ensured
+// via a lock file next to the socket. If a previous Server has crashed and left
+// its socket behind, the stale socket is removed.
 func (s *Server) Run(ctx context.Context, pp []Process) error {
 	if s.logger == nil {
 		s.logger = s.Executor.proxLogger(pp)
 	}
 
 	var err error
-	s.listener, err = net.Listen("unix", s.socketPath)
+	s.lock, err = acquireInstanceLock(s.socketPath + ".lock")
+	if err != nil {
+		s.logger.Error(err.Error())
+		return err
+	}
+
+	s.listener, err = listenUnix(s.socketPath)
 	if err != nil {
 		s.logger.Error("Failed to open unix socket: " + err.Error())
 		return errors.Wrap(err, "failed to open unix socket")
 	}
 
+	if s.httpAddr != "" {
+		s.httpListener, err = listenHTTP(s.httpAddr)
+		if err != nil {
+			s.logger.Error("Failed to open HTTP listener: " + err.Error())
+			return errors.Wrap(err, "failed to open HTTP listener")
+		}
+	}
+
+	if s.remoteAddr != "" {
+		err = s.listenRemote()
+		if err != nil {
+			s.logger.Error("Failed to listen for remote clients: " + err.Error())
+			return errors.Wrap(err, "failed to listen for remote clients")
+		}
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel() // always cancel context even if Executor finishes normally
 
-	go s.acceptConnections(ctx)
-	return s.Executor.Run(ctx, pp)
+	// The Executor uses its own context so clients can request a shutdown but
+	// stay connected until all processes have actually finished.
+	executorCtx, shutdown := context.WithCancel(ctx)
+	defer shutdown()
+	s.shutdown = shutdown
+
+	s.serve(ctx)
+	return s.Executor.Run(executorCtx, pp)
+}
+
+// SetProcessLoader sets the function that is used to read the configuration of
+// all processes again when a client requests to reload the configuration. If
+// no loader is set, reloading via the Server is not supported.
+func (s *Server) SetProcessLoader(load func() ([]Process, error)) {
+	s.load = load
+}
+
+// serve starts to accept connections on the listener of the Server in a new
+// goroutine. All connections are closed when the context is done or the
+// Server is closed.
+func (s *Server) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	s.stopServing = cancel
+	s.mu.Unlock()
+
+	s.serveHTTP(ctx)
+	s.serveGRPC(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.acceptConnections(ctx)
+	}()
 }
 
 func (s *Server) acceptConnections(ctx context.Context) {
@@ -76,124 +155,174 @@ func (s *Server) acceptConnections(ctx context.Context) {
 		clientID++
 		connLog := s.logger.With(zap.Int("client_id", clientID))
 		connLog.Debug("Accepted new socket connection from prox client")
-		go s.handleConnection(ctx, conn, connLog)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.dispatchConnection(conn, connLog)
+		}()
 	}
 }
 
-func (s *Server) handleConnection(ctx context.Context, conn net.Conn, logger *zap.Logger) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel() // Always make sure the connection is closed when we return.
-
-	go func() {
-		<-ctx.Done()
-		logger.Info("Closing connection to prox client")
-		err := conn.Close()
-		if err != nil && !isClosedConnectionError(err) {
-			logger.Error("Failed to close connection", zap.Error(err))
-		}
-	}()
-
-	msg, err := s.readMessage(conn)
-	if errors.Cause(err) == io.EOF {
-		logger.Error("Lost connection to prox client")
+// dispatchConnection passes a new connection either to the gRPC server or to
+// the HTTP API.
+func (s *Server) dispatchConnection(conn net.Conn, logger *zap.Logger) {
+	conn, isGRPC, err := sniffGRPC(conn)
+	switch {
+	case err == io.EOF:
+		// e.g. a client that only checks if the socket accepts connections
+		logger.Debug("Connection was closed before sending any data")
+		conn.Close()
 		return
-	}
-	if err != nil {
-		logger.Error("Failed to read message from client", zap.Error(err))
+	case err != nil:
+		logger.Error("Failed to read from new connection", zap.Error(err))
+		conn.Close()
 		return
 	}
 
-	logger.Info("Received command from prox client", zap.Any("msg", msg))
-
-	switch {
-	case msg.Command == "LIST":
-		err = s.handleListCommand(ctx, conn, msg, logger)
-	case msg.Command == "TAIL":
-		err = s.handleTailCommand(ctx, conn, msg, logger)
-	case msg.Command == "EXIT":
-		logger.Info("Prox client has closed the connection")
-		return
-	default:
-		logger.Error("Unknown command from prox client", zap.Any("msg", msg))
-		return
+	l := s.httpConns
+	if isGRPC {
+		logger.Debug("Passing connection to gRPC server")
+		l = s.grpcConns
+	} else {
+		logger.Debug("Passing connection to HTTP API")
 	}
 
-	if err != nil && !isClosedConnectionError(err) {
-		// TODO: send messages back to client
-		logger.Error("prox client error", zap.Error(err))
+	if !l.push(conn) {
+		conn.Close()
 	}
 }
 
-func (s *Server) readMessage(conn net.Conn) (socketMessage, error) {
-	var msg socketMessage
-	err := json.NewDecoder(conn).Decode(&msg)
+// grpcPreface is the beginning of the connection preface of HTTP/2 which is
+// used by gRPC clients.
+const grpcPreface = "PRI"
+
+// sniffTimeout is the maximum time the Server waits for the first bytes of a
+// new connection to decide whether it speaks gRPC or HTTP/1.
+const sniffTimeout = 5 * time.Second
+
+// sniffGRPC peeks at the first bytes of a new connection to determine if the
+// client speaks gRPC or HTTP/1. The returned connection must be used instead
+// of the given one.
+func sniffGRPC(conn net.Conn) (net.Conn, bool, error) {
+	r := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	b, err := r.Peek(len(grpcPreface))
+	conn.SetReadDeadline(time.Time{})
 	if err != nil {
-		return msg, errors.Wrap(err, "failed to decode message")
+		return conn, false, err
 	}
 
-	return msg, nil
+	return bufferedConn{Conn: conn, r: r}, string(b) == grpcPreface, nil
 }
 
-func (s *Server) handleListCommand(ctx context.Context, conn net.Conn, msg socketMessage, logger *zap.Logger) error {
-	var names []string
-	for name := range s.Executor.running {
-		names = append(names, name)
-	}
+// A bufferedConn is a net.Conn whose reads go through a bufio.Reader.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
 
-	sort.Strings(names)
-	resp := make([]ProcessInfo, len(names))
-	for i, name := range names {
-		resp[i] = s.Executor.Info(name)
-	}
+func (c bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
 
-	return json.NewEncoder(conn).Encode(resp)
+// A connListener is a net.Listener that returns the connections that were
+// passed to it. It is used to pass the connections of the unix socket to the
+// gRPC server or the http.Server.
+type connListener struct {
+	addr   net.Addr
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
 }
 
-func (s *Server) handleTailCommand(ctx context.Context, conn net.Conn, msg socketMessage, logger *zap.Logger) error {
-	if len(msg.Args) == 0 {
-		return errors.New("no arguments for tail provided")
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{
+		addr:   addr,
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
 	}
+}
 
-	var outputs []*multiWriter
-	for _, name := range msg.Args {
-		o, ok := s.Executor.outputs[name]
-		if !ok {
-			return errors.Errorf("cannot tail unknown process %q", name)
-		}
+// push passes a connection to Accept. It returns false if the listener was
+// closed.
+func (l *connListener) push(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.closed:
+		return false
+	}
+}
 
-		o.AddWriter(conn)
-		outputs = append(outputs, o)
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errors.New("use of closed network connection")
 	}
+}
 
-	defer func() {
-		for _, o := range outputs {
-			o.RemoveWriter(conn)
-		}
-	}()
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
 
-	msg, err := s.readMessage(conn)
-	if err != nil {
-		return err
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
+
+// reload reads the configuration of all processes again via the process loader
+// and applies it to the Executor.
+func (s *Server) reload() (ReloadDiff, error) {
+	if s.load == nil {
+		return ReloadDiff{}, serverErrorf(CodeNotSupported, "reloading is not supported by this server")
 	}
 
-	if msg.Command != "EXIT" {
-		return errors.Errorf("expected EXIT command but got %q", msg.Command)
+	pp, err := s.load()
+	if err != nil {
+		return ReloadDiff{}, errors.Wrap(err, "failed to load processes")
 	}
 
-	logger.Info("Client closed TAIL connection")
-	return nil
+	return s.Executor.Reload(pp)
 }
 
-// Close closes the Servers listener.
+// Close closes the Servers listener and all open connections and releases its
+// lock. It blocks until all connections have been closed.
 func (s *Server) Close() error {
+	defer s.lock.release()
 	if s.listener == nil {
 		return nil
 	}
 
-	// TODO: improve closing (wait until listener loop has actually finished)
+	s.mu.Lock()
+	if s.stopServing != nil {
+		s.stopServing()
+	}
+	s.mu.Unlock()
 
 	s.logger.Info("Closing unix socket")
-	return s.listener.Close()
+	err := s.listener.Close()
+	if s.http != nil {
+		s.http.Close()
+	}
+	if s.grpc != nil {
+		s.grpc.GracefulStop() // all streams have been closed via stopServing
+	}
+	if s.remoteGRPC != nil {
+		s.remoteGRPC.GracefulStop()
+	} else if s.remoteListener != nil {
+		s.remoteListener.Close()
+	}
+	s.wg.Wait()
+
+	if err != nil && isClosedConnectionError(err) {
+		return nil // already closed
+	}
+
+	return err
 }
 
 func isClosedConnectionError(err error) bool {
@@ -2,11 +2,20 @@ package prox
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/fgrosse/prox/proxpb"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
+	"google.golang.org/grpc/connectivity"
 )
 
 var _ = Describe("Server", func() {
@@ -31,7 +40,7 @@ var _ = Describe("Server", func() {
 			go func() {
 				defer GinkgoRecover()
 				sync <- true
-				err := client.Tail(ctx, []string{"p2"}, output)
+				err := tail(ctx, client, []string{"p2"}, TailOptions{}, output)
 				Expect(err).NotTo(HaveOccurred())
 			}()
 
@@ -49,6 +58,448 @@ var _ = Describe("Server", func() {
 			Eventually(output).Should(Say("A message from p2"))
 			Eventually(output).Should(Say("And another message from p2"))
 		})
+
+		It("should return the recent output before following it", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			go executor.Run(p1)
+			Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+			p1.ShouldSay(t, "line 1\n")
+			p1.ShouldSay(t, "line 2\n")
+			p1.ShouldSay(t, "line 3\n")
+			Eventually(func() int { return len(recentOutput(executor.Executor)) }).Should(Equal(3))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			output := NewBuffer()
+			go func() {
+				defer GinkgoRecover()
+				err := tail(ctx, client, []string{"p1"}, TailOptions{History: 2}, output)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			Eventually(output).Should(Say("line 2\nline 3\n"))
+			Expect(output.Contents()).NotTo(ContainSubstring("line 1"))
+
+			p1.ShouldSay(t, "line 4\n")
+			Eventually(output).Should(Say("line 4\n"))
+		})
+
+		It("should only return the recent output if it should not follow", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			go executor.Run(p1)
+			Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+			p1.ShouldSay(t, "line 1\n")
+			p1.ShouldSay(t, "line 2\n")
+			Eventually(func() int { return len(recentOutput(executor.Executor)) }).Should(Equal(2))
+
+			output := NewBuffer()
+			err := tail(context.Background(), client, []string{"p1"}, TailOptions{History: -1, NoFollow: true}, output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output.Contents())).To(Equal("line 1\nline 2\n"))
+		})
+
+		It("should select the processes via patterns", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			api1 := &TestProcess{name: "api-1"}
+			api2 := &TestProcess{name: "api-2"}
+			worker := &TestProcess{name: "worker"}
+			go executor.Run(api1, api2, worker)
+			Eventually(api1.HasBeenStarted).Should(BeTrue())
+			Eventually(api2.HasBeenStarted).Should(BeTrue())
+			Eventually(worker.HasBeenStarted).Should(BeTrue())
+
+			api1.ShouldSay(t, "api 1\n")
+			worker.ShouldSay(t, "worker\n")
+			api2.ShouldSay(t, "api 2\n")
+			Eventually(func() int { return len(recentOutput(executor.Executor)) }).Should(Equal(3))
+
+			tail := func(patterns ...string) (string, error) {
+				output := NewBuffer()
+				opts := TailOptions{History: -1, NoFollow: true}
+				err := tail(context.Background(), client, patterns, opts, output)
+				return string(output.Contents()), err
+			}
+
+			Expect(tail("api-*")).To(Equal("api 1\napi 2\n"))
+			Expect(tail(AllProcesses, "!api-2")).To(Equal("api 1\nworker\n"))
+			Expect(tail("!worker")).To(Equal("api 1\napi 2\n"))
+
+			_, err := tail("db-*")
+			Expect(err).To(Equal(&ServerError{Code: CodeNotFound, Message: `no process matches "db-*"`}))
+
+			_, err = tail("api-*", "!db")
+			Expect(err).To(Equal(&ServerError{Code: CodeNotFound, Message: `cannot tail unknown process "db"`}))
+		})
+	})
+
+	Describe("Tail with filters", func() {
+		It("should only return the matching output", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			go executor.Run(p1)
+			Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+			p1.ShouldSay(t, "user_id=42 old\n")
+			p1.ShouldSay(t, "user_id=43 old\n")
+			Eventually(func() int { return len(recentOutput(executor.Executor)) }).Should(Equal(2))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			output := NewBuffer()
+			go func() {
+				defer GinkgoRecover()
+				opts := TailOptions{History: -1, Where: []string{"user_id=42"}}
+				err := tail(ctx, client, []string{"p1"}, opts, output)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			Eventually(output).Should(Say("user_id=42 old\n"))
+
+			p1.ShouldSay(t, "user_id=43 new\n")
+			p1.ShouldSay(t, "user_id=42 new\n")
+			Eventually(output).Should(Say("user_id=42 new\n"))
+			Expect(output.Contents()).NotTo(ContainSubstring("user_id=43"))
+		})
+
+		It("should apply the filter before limiting the recent output", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			go executor.Run(p1)
+			Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+			p1.ShouldSay(t, "ERROR 1\n")
+			p1.ShouldSay(t, "ERROR 2\n")
+			p1.ShouldSay(t, "INFO 3\n")
+			Eventually(func() int { return len(recentOutput(executor.Executor)) }).Should(Equal(3))
+
+			output := NewBuffer()
+			opts := TailOptions{History: 1, NoFollow: true, Level: "error"}
+			err := tail(context.Background(), client, []string{"p1"}, opts, output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output.Contents())).To(Equal("ERROR 2\n"))
+		})
+	})
+
+	Describe("Tail with FollowRestarts", func() {
+		It("should resume following the output when the server was restarted", func() {
+			dir, err := ioutil.TempDir("", "prox")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			socketPath := filepath.Join(dir, "prox.sock")
+			run := func(line string) (stop func()) {
+				server := NewExecutorServer(socketPath, true)
+				server.Executor.output = GinkgoWriter
+
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan error, 1)
+				go func() {
+					done <- server.Run(ctx, []Process{{Name: "p1", Runner: outputRunner(line)}})
+				}()
+
+				return func() {
+					cancel()
+					Eventually(done).Should(Receive())
+					server.Close()
+				}
+			}
+
+			stop := run("first run")
+			var client *Client
+			Eventually(func() error {
+				client, err = NewClient(socketPath, false)
+				return err
+			}).Should(Succeed())
+			defer client.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			output := NewBuffer()
+			tailDone := make(chan error, 1)
+			go func() {
+				opts := TailOptions{History: -1, FollowRestarts: true}
+				tailDone <- tail(ctx, client, []string{"p1"}, opts, output)
+			}()
+
+			Eventually(output).Should(Say("first run\n"))
+			stop()
+			Consistently(tailDone).ShouldNot(Receive(), "the client should wait for the server")
+
+			stop = run("second run")
+			defer stop()
+
+			Eventually(output, "5s").Should(Say(`gap of \d+s\n`))
+			Eventually(output).Should(Say("second run\n"))
+			Expect(strings.Count(string(output.Contents()), "first run")).To(Equal(1), "the output of the first run should not be repeated")
+
+			cancel()
+			Eventually(tailDone).Should(Receive(BeNil()))
+		})
+
+		It("should resume the stream of the same server by sequence number", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			go executor.Run(p1)
+			Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+			p1.ShouldSay(t, "first\nsecond\nthird\n")
+			Eventually(func() int { return len(recentOutput(executor.Executor)) }).Should(Equal(3))
+
+			resume := func(instance string, since uint64) []string {
+				req := &proxpb.TailRequest{Processes: []string{"p1"}, History: -1, NoFollow: true, Instance: instance, SinceSeq: since}
+				stream, serverInstance, err := client.tail(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(serverInstance).To(Equal(executor.history.instance))
+
+				var lines []string
+				for {
+					l, err := stream.Recv()
+					if err == io.EOF {
+						return lines
+					}
+					Expect(err).NotTo(HaveOccurred())
+					lines = append(lines, l.Line)
+				}
+			}
+
+			Expect(resume(executor.history.instance, 1)).To(Equal([]string{"second", "third"}))
+			Expect(resume("restarted", 1)).To(Equal([]string{"first", "second", "third"}), "sequence numbers of other instances should be ignored")
+		})
+	})
+
+	Describe("Events", func() {
+		It("should stream the filtered lifecycle events to the Client", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			p2 := &TestProcess{name: "p2"}
+
+			ctx := context.Background()
+			output := NewBuffer()
+
+			go func() {
+				defer GinkgoRecover()
+				filter := EventFilter{Processes: []string{"p2"}}
+				events, err := client.Events(ctx, filter)
+				Expect(err).NotTo(HaveOccurred())
+
+				enc := json.NewEncoder(output)
+				for e := range events {
+					Expect(enc.Encode(e)).To(Succeed())
+				}
+			}()
+
+			Eventually(executor.events.subscriberCount).Should(Equal(1))
+			go executor.Run(p1, p2)
+			EventuallyAllProcessesShouldHaveStarted(p1, p2)
+
+			Eventually(output).Should(Say(`"type":"started","process":"p2"`))
+			p2.Finish()
+			Eventually(output).Should(Say(`"type":"exited","process":"p2","exit_code":0`))
+
+			p1.Finish()
+			Eventually(output).Should(Say(`"type":"shutdown"`))
+			Expect(string(output.Contents())).NotTo(ContainSubstring(`"process":"p1"`))
+		})
+	})
+
+	Describe("Reload", func() {
+		It("should return an error if the server cannot load processes", func() {
+			t := GinkgoT()
+			_, client, _, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			_, err := client.Reload(context.Background())
+			Expect(err).To(MatchError("reloading is not supported by this server"))
+		})
+	})
+
+	Describe("Signal", func() {
+		It("should return an error if the process does not exist", func() {
+			t := GinkgoT()
+			_, client, executor, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			go executor.Run(p1)
+			Eventually(p1.HasBeenStarted).Should(BeTrue())
+
+			_, err := client.Signal(context.Background(), []string{"p2"}, "SIGUSR1", false)
+			Expect(err).To(MatchError(`no such process "p2"`))
+		})
+	})
+
+	Describe("Shutdown", func() {
+		It("should stop all processes and wait until they have finished", func() {
+			dir, err := ioutil.TempDir("", "prox")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			output := NewBuffer()
+			socketPath := filepath.Join(dir, "prox.sock")
+			server := NewExecutorServer(socketPath, true)
+			server.Executor.output = output
+			defer server.Close()
+
+			interrupted := make(chan bool, 1)
+			done := make(chan error, 1)
+			go func() {
+				done <- server.Run(context.Background(), []Process{{
+					Name: "p1",
+					Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+						<-ctx.Done()
+						time.Sleep(50 * time.Millisecond) // graceful shutdown takes some time
+						interrupted <- true
+						return ctx.Err()
+					}),
+				}})
+			}()
+
+			var client *Client
+			Eventually(func() error {
+				client, err = NewClient(socketPath, false)
+				return err
+			}).Should(Succeed())
+			defer client.Close()
+
+			Expect(client.Shutdown(context.Background())).To(Succeed())
+			Expect(interrupted).To(Receive(), "client should wait until the process has finished")
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(output).To(Say("Received interrupt signal"))
+		})
+
+		It("should return an error if the server does not support shutdowns", func() {
+			t := GinkgoT()
+			_, client, _, done := TestNewServerAndClient(t, GinkgoWriter)
+			defer done()
+
+			err := client.Shutdown(context.Background())
+			Expect(err).To(MatchError("shutdown is not supported by this server"))
+		})
+	})
+
+	Describe("Process control", func() {
+		It("should start, stop and restart single processes", func() {
+			dir, err := ioutil.TempDir("", "prox")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			socketPath := filepath.Join(dir, "prox.sock")
+			server := NewExecutorServer(socketPath, true)
+			server.Executor.output = GinkgoWriter
+			defer server.Close()
+
+			runner := &countingRunner{starts: map[string]int{}}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- server.Run(ctx, []Process{runner.process("p1"), runner.process("p2")})
+			}()
+			defer func() {
+				cancel()
+				Eventually(done).Should(Receive())
+			}()
+
+			var client *Client
+			Eventually(func() error {
+				client, err = NewClient(socketPath, false)
+				return err
+			}).Should(Succeed())
+			defer client.Close()
+
+			Eventually(runner.Running).Should(ConsistOf("p1", "p2"))
+
+			Expect(client.StopProcesses(ctx, "p1")).To(Equal([]string{"p1"}))
+			Eventually(runner.Running).Should(ConsistOf("p2"))
+
+			_, err = client.StopProcesses(ctx, "p1")
+			Expect(err).To(Equal(&ServerError{Code: CodeConflict, Message: `process "p1" is not running`}))
+
+			Expect(client.StartProcesses(ctx, "p1")).To(Equal([]string{"p1"}))
+			Eventually(runner.Running).Should(ConsistOf("p1", "p2"))
+
+			Expect(client.RestartProcesses(ctx, "p2")).To(Equal([]string{"p2"}))
+			Eventually(func() int { return runner.Starts("p2") }).Should(Equal(2))
+
+			_, err = client.RestartProcesses(ctx, "unknown")
+			Expect(err).To(Equal(&ServerError{Code: CodeNotFound, Message: `no such process "unknown"`}))
+		})
+
+		It("should select processes via patterns", func() {
+			dir, err := ioutil.TempDir("", "prox")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			socketPath := filepath.Join(dir, "prox.sock")
+			server := NewExecutorServer(socketPath, true)
+			server.Executor.output = GinkgoWriter
+			defer server.Close()
+
+			runner := &countingRunner{starts: map[string]int{}}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- server.Run(ctx, []Process{runner.process("api-1"), runner.process("api-2"), runner.process("worker")})
+			}()
+			defer func() {
+				cancel()
+				Eventually(done).Should(Receive())
+			}()
+
+			var client *Client
+			Eventually(func() error {
+				client, err = NewClient(socketPath, false)
+				return err
+			}).Should(Succeed())
+			defer client.Close()
+
+			Eventually(runner.Running).Should(ConsistOf("api-1", "api-2", "worker"))
+
+			Expect(client.RestartProcesses(ctx, "api-*")).To(Equal([]string{"api-1", "api-2"}))
+			Eventually(func() int { return runner.Starts("api-2") }).Should(Equal(2))
+			Expect(runner.Starts("worker")).To(Equal(1))
+
+			Expect(client.StopProcesses(ctx, AllProcesses, "!worker")).To(Equal([]string{"api-1", "api-2"}))
+			Eventually(runner.Running).Should(ConsistOf("worker"))
+
+			Expect(client.StartProcesses(ctx, "!worker")).To(Equal([]string{"api-1", "api-2"}))
+			Eventually(runner.Running).Should(ConsistOf("api-1", "api-2", "worker"))
+
+			_, err = client.StopProcesses(ctx, "db-*")
+			Expect(err).To(Equal(&ServerError{Code: CodeNotFound, Message: `no process matches "db-*"`}))
+
+			_, err = client.StopProcesses(ctx, "api-*", "!api-?")
+			Expect(err).To(Equal(&ServerError{Code: CodeNotFound, Message: `all processes are excluded by "api-* !api-?"`}))
+
+			_, err = client.StopProcesses(ctx, "api-[")
+			Expect(err).To(Equal(&ServerError{Code: CodeBadRequest, Message: `invalid process pattern "api-["`}))
+			Expect(runner.Running()).To(ConsistOf("api-1", "api-2", "worker"))
+		})
 	})
 
 	Describe("List", func() {
@@ -62,21 +513,115 @@ var _ = Describe("Server", func() {
 
 			go executor.Run(p1, p2)
 
-			ctx := context.Background()
-			output := NewBuffer()
+			Eventually(p1.HasBeenStarted).Should(BeTrue())
+			Eventually(p2.HasBeenStarted).Should(BeTrue())
 
+			infos, err := client.List(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(infos).To(HaveLen(2))
+			Expect(infos[0].Name).To(Equal("p1"))
+			Expect(infos[0].PID).To(Equal(101))
+			Expect(infos[0].State).To(Equal(StateRunning))
+			Expect(infos[1].Name).To(Equal("p2"))
+			Expect(infos[1].PID).To(Equal(102))
+		})
+	})
+
+	Describe("Concurrent commands", func() {
+		It("should keep serving other commands on the connection when a stream is cancelled", func() {
+			t := GinkgoT()
+			logs := NewBuffer()
+			_, client, executor, done := TestNewServerAndClient(t, io.MultiWriter(logs, GinkgoWriter))
+			defer done()
+
+			p1 := &TestProcess{name: "p1"}
+			p2 := &TestProcess{name: "p2"}
+			go executor.Run(p1, p2)
 			Eventually(p1.HasBeenStarted).Should(BeTrue())
 			Eventually(p2.HasBeenStarted).Should(BeTrue())
 
+			ctx1, cancel1 := context.WithCancel(context.Background())
+			defer cancel1()
+			lines1, err := client.Tail(ctx1, []string{"p1"}, TailOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx2, cancel2 := context.WithCancel(context.Background())
+			defer cancel2()
+			lines2, err := client.Tail(ctx2, []string{"p2"}, TailOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			var l LogLine
+			p1.ShouldSay(t, "A message from p1\n")
+			Eventually(lines1).Should(Receive(&l))
+			Expect(l.Line).To(Equal("A message from p1"))
+
+			// unary calls are running on the same connection while the first
+			// stream is cancelled
+			listErrs := make(chan error, 1)
 			go func() {
 				defer GinkgoRecover()
-				err := client.List(ctx, output)
-				Expect(err).NotTo(HaveOccurred())
+				for i := 0; i < 20; i++ {
+					infos, err := client.List(context.Background())
+					if err != nil {
+						listErrs <- err
+						return
+					}
+					Expect(infos).To(HaveLen(2))
+				}
+				listErrs <- nil
 			}()
 
-			Eventually(output).Should(Say("NAME    PID"))
-			Eventually(output).Should(Say("p1      101"))
-			Eventually(output).Should(Say("p2      102"))
+			cancel1()
+			Eventually(lines1).Should(BeClosed())
+			Eventually(logs).Should(Say(`Prox client has closed the stream.*/prox.v1.Prox/Tail`))
+			Eventually(listErrs).Should(Receive(BeNil()))
+
+			p2.ShouldSay(t, "A message from p2\n")
+			Eventually(lines2).Should(Receive(&l))
+			Expect(l.Line).To(Equal("A message from p2"))
+
+			infos, err := client.List(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(infos).To(HaveLen(2))
+			Expect(client.conn.GetState()).To(Equal(connectivity.Ready), "the connection should still be open")
 		})
 	})
 })
+
+// outputRunner prints a line and runs until it is stopped.
+type outputRunner string
+
+func (r outputRunner) Run(ctx context.Context, w io.Writer) error {
+	fmt.Fprintln(w, string(r))
+	<-ctx.Done()
+	return nil
+}
+
+// tail writes the raw lines and the gaps that are received via Client.Tail to
+// the output and returns the error that has ended the stream.
+func tail(ctx context.Context, c *Client, patterns []string, opts TailOptions, output io.Writer) error {
+	lines, err := c.Tail(ctx, patterns, opts)
+	if err != nil {
+		return err
+	}
+
+	for l := range lines {
+		switch {
+		case l.Err != nil:
+			return l.Err
+		case l.Gap != nil:
+			fmt.Fprintf(output, "gap of %s\n", l.Gap.Reconnected.Sub(l.Gap.Disconnected).Round(time.Second))
+		default:
+			fmt.Fprintln(output, l.Line)
+		}
+	}
+
+	return nil
+}
+
+// recentOutput returns the output history of all processes of the Executor.
+func recentOutput(e *Executor) []OutputLine {
+	history, _, unsubscribe := e.history.subscribe(func(string) bool { return true }, 0, -1)
+	unsubscribe()
+	return history
+}
@@ -0,0 +1,135 @@
+package prox
+
+import (
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// terminatingSignals are the signals which are expected to end a process.
+var terminatingSignals = map[syscall.Signal]bool{
+	syscall.SIGINT:  true,
+	syscall.SIGKILL: true,
+	syscall.SIGQUIT: true,
+	syscall.SIGTERM: true,
+}
+
+// signalGracePeriod is the time in which a process must exit after it has
+// received a terminating signal in order to not be treated as crash.
+var signalGracePeriod = 5 * time.Second
+
+// signals contains all signals that can be sent to processes by name.
+var signals = map[string]syscall.Signal{
+	"SIGABRT":   syscall.SIGABRT,
+	"SIGALRM":   syscall.SIGALRM,
+	"SIGCONT":   syscall.SIGCONT,
+	"SIGHUP":    syscall.SIGHUP,
+	"SIGINT":    syscall.SIGINT,
+	"SIGKILL":   syscall.SIGKILL,
+	"SIGPIPE":   syscall.SIGPIPE,
+	"SIGQUIT":   syscall.SIGQUIT,
+	"SIGSTOP":   syscall.SIGSTOP,
+	"SIGTERM":   syscall.SIGTERM,
+	"SIGTSTP":   syscall.SIGTSTP,
+	"SIGTTIN":   syscall.SIGTTIN,
+	"SIGTTOU":   syscall.SIGTTOU,
+	"SIGUSR1":   syscall.SIGUSR1,
+	"SIGUSR2":   syscall.SIGUSR2,
+	"SIGWINCH":  syscall.SIGWINCH,
+	"SIGPROF":   syscall.SIGPROF,
+	"SIGTRAP":   syscall.SIGTRAP,
+	"SIGVTALRM": syscall.SIGVTALRM,
+}
+
+// ParseSignal parses a signal by its name (e.g. "SIGUSR1", "USR1" or "usr1")
+// or by its number (e.g. "10").
+func ParseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n <= 0 {
+			return 0, errors.Errorf("invalid signal number %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+
+	sig, ok := signals[name]
+	if !ok {
+		return 0, errors.Errorf("unknown signal %q", s)
+	}
+
+	return sig, nil
+}
+
+// A signaler is a process that supports receiving arbitrary signals.
+type signaler interface {
+	Signal(sig syscall.Signal, group bool) error
+}
+
+// Signal sends a signal to a running process. If group is true, the signal is
+// sent to the whole process group of the process (i.e. also to all of its
+// child processes).
+//
+// If the signal is SIGINT, SIGTERM, SIGQUIT or SIGKILL and the process exits
+// within signalGracePeriod, its termination is not treated as crash and thus
+// it does not stop the other processes. Processes that trap the signal and
+// exit later or that exit after any other signal are treated as usual.
+//
+// SIGSTOP and SIGCONT update the paused state of the process like
+// PauseProcess and ResumeProcess do so its timeouts are suspended while it is
+// stopped. SIGCONT is always sent to the whole process group of a paused
+// process because PauseProcess has stopped all of its children as well.
+func (e *Executor) Signal(name string, sig syscall.Signal, group bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p, ok := e.running[name]
+	if !ok {
+		return noSuchProcessError(name)
+	}
+
+	sp, ok := p.(signaler)
+	if !ok {
+		return errors.Errorf("process %q does not support signals", name)
+	}
+
+	run := e.runs[name]
+	if sig == syscall.SIGCONT && run != nil && run.isPaused() {
+		group = true
+	}
+
+	if e.logger != nil {
+		e.logger.Info("Sending signal to process",
+			zap.String("process_name", name),
+			zap.Stringer("signal", sig),
+			zap.Bool("process_group", group),
+		)
+	}
+
+	err := sp.Signal(sig, group)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send %v to process %q", sig, name)
+	}
+
+	if terminatingSignals[sig] {
+		e.signaled[p] = time.Now()
+	}
+
+	if run != nil {
+		switch sig {
+		case syscall.SIGSTOP:
+			run.pause()
+		case syscall.SIGCONT:
+			run.resume()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package prox
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ParseSignal", func() {
+	DescribeTable("valid signals",
+		func(input string, expected syscall.Signal) {
+			sig, err := ParseSignal(input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sig).To(Equal(expected))
+		},
+
+		Entry("full name", "SIGUSR1", syscall.SIGUSR1),
+		Entry("without prefix", "HUP", syscall.SIGHUP),
+		Entry("lower case", "quit", syscall.SIGQUIT),
+		Entry("number", "15", syscall.SIGTERM),
+	)
+
+	It("should return an error for unknown signals", func() {
+		_, err := ParseSignal("SIGFOO")
+		Expect(err).To(MatchError(`unknown signal "SIGFOO"`))
+	})
+
+	It("should return an error for invalid signal numbers", func() {
+		_, err := ParseSignal("-1")
+		Expect(err).To(MatchError(`invalid signal number -1`))
+	})
+})
+
+var _ = Describe("Executor.Signal", func() {
+	It("should not treat a signal-induced exit as crash", func() {
+		output := NewBuffer()
+		executor := TestNewExecutor(output)
+		executor.DisableColoredOutput()
+
+		env := NewEnv([]string{"GO_WANT_HELPER_PROCESS=1"})
+		processes := []Process{
+			{Name: "signaled", Script: testProcessScript("echo", "-block", "-exit", "2"), Env: env},
+			{Name: "other", Script: testProcessScript("echo", "-block"), Env: env},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- executor.Executor.Run(ctx, processes)
+		}()
+
+		Eventually(output).Should(Say(`signaled\s+│ Waiting for os signal`))
+		Eventually(executor.runningProcesses).Should(ConsistOf("signaled", "other"))
+
+		Expect(executor.Signal("signaled", syscall.SIGTERM, false)).To(Succeed())
+		Eventually(executor.runningProcesses).Should(ConsistOf("other"))
+		Consistently(done).ShouldNot(Receive(), "the other process should keep running")
+
+		cancel()
+		Eventually(done, "5s").Should(Receive(BeNil()))
+	})
+
+	It("should treat an exit after a non-terminating signal as crash", func() {
+		dir, err := ioutil.TempDir("", "prox")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		crash := filepath.Join(dir, "crash")
+
+		output := NewBuffer()
+		executor := TestNewExecutor(output)
+		executor.DisableColoredOutput()
+
+		env := NewEnv([]string{"GO_WANT_HELPER_PROCESS=1"})
+		processes := []Process{
+			{Name: "signaled", Script: fmt.Sprintf(`sh -c 'trap "echo trapped" USR1; echo ready; while [ ! -f %s ]; do sleep 0.05; done; exit 3'`, crash)},
+			{Name: "other", Script: testProcessScript("echo", "-block"), Env: env},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- executor.Executor.Run(ctx, processes)
+		}()
+
+		Eventually(output).Should(Say(`signaled\s+│ ready`))
+		Eventually(executor.runningProcesses).Should(ConsistOf("signaled", "other"))
+
+		Expect(executor.Signal("signaled", syscall.SIGUSR1, false)).To(Succeed())
+		Eventually(output).Should(Say(`signaled\s+│ trapped`))
+		Expect(executor.runningProcesses()).To(ConsistOf("signaled", "other"))
+
+		Expect(ioutil.WriteFile(crash, nil, 0600)).To(Succeed())
+		Eventually(done, "5s").Should(Receive(HaveOccurred()), "the crash should stop the stack")
+	})
+
+	It("should treat a crash after a trapped terminating signal as crash", func() {
+		defer func(d time.Duration) { signalGracePeriod = d }(signalGracePeriod)
+		signalGracePeriod = 100 * time.Millisecond
+
+		dir, err := ioutil.TempDir("", "prox")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		crash := filepath.Join(dir, "crash")
+
+		output := NewBuffer()
+		executor := TestNewExecutor(output)
+		executor.DisableColoredOutput()
+
+		env := NewEnv([]string{"GO_WANT_HELPER_PROCESS=1"})
+		processes := []Process{
+			{Name: "signaled", Script: fmt.Sprintf(`sh -c 'trap "echo trapped" TERM; echo ready; while [ ! -f %s ]; do sleep 0.05; done; exit 3'`, crash)},
+			{Name: "other", Script: testProcessScript("echo", "-block"), Env: env},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- executor.Executor.Run(ctx, processes)
+		}()
+
+		Eventually(output).Should(Say(`signaled\s+│ ready`))
+		Eventually(executor.runningProcesses).Should(ConsistOf("signaled", "other"))
+
+		Expect(executor.Signal("signaled", syscall.SIGTERM, false)).To(Succeed())
+		Eventually(output).Should(Say(`signaled\s+│ trapped`))
+		time.Sleep(2 * signalGracePeriod)
+		Expect(executor.runningProcesses()).To(ConsistOf("signaled", "other"))
+
+		Expect(ioutil.WriteFile(crash, nil, 0600)).To(Succeed())
+		Eventually(done, "5s").Should(Receive(HaveOccurred()), "the crash should stop the stack")
+	})
+
+	It("should return an error if the process is not running", func() {
+		executor := TestNewExecutor(GinkgoWriter)
+		err := executor.Signal("foo", syscall.SIGUSR1, false)
+		Expect(err).To(MatchError(`no such process "foo"`))
+	})
+})
@@ -1,7 +1,6 @@
 package prox
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/fgrosse/zaptest"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 type TestExecutor struct { // TODO: no need to export these types
@@ -47,12 +47,14 @@ func (e *TestExecutor) Run(processes ...*TestProcess) {
 		}
 	}
 
+	e.Executor.mu.Lock()
+	e.out = output
 	pp := make([]process, len(processes))
 	for i, p := range processes {
-		e.outputs[p.name] = output.next(Process{Name: p.name})
-		p.output = e.outputs[p.name]
+		p.output = e.processOutput(output, Process{Name: p.name})
 		pp[i] = p
 	}
+	e.Executor.mu.Unlock()
 
 	e.mu.Lock()
 	e.executorDone = false
@@ -229,17 +231,16 @@ func TestNewServerAndClient(t TestReporter, w io.Writer) (server *Server, client
 		t.Fatal(err)
 	}
 
-	client.conn, err = net.Dial("tcp", server.listener.Addr().String())
+	ctx := context.Background()
+	server.serve(ctx)
+
+	c, err := dialClient(server.listener.Addr().String(), client.logger, grpc.WithInsecure())
 	if err != nil {
-		client.conn = nil
 		done()
 		t.Fatal(err)
 	}
 
-	client.buf = bufio.NewReader(client.conn)
-
-	ctx := context.Background()
-	go server.acceptConnections(ctx)
+	*client = *c
 
 	return server, client, executor, done
 }
@@ -0,0 +1,236 @@
+package prox
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Errors which are returned (wrapped) by the Executor if a process has exceeded
+// one of its timeouts. Use errors.Cause(…) to compare them.
+var (
+	ErrMaxRuntimeExceeded   = errors.New("maximum runtime exceeded")
+	ErrStartTimeoutExceeded = errors.New("start timeout exceeded")
+)
+
+// isTimeout returns true if the error was caused by a process timeout.
+func isTimeout(err error) bool {
+	cause := errors.Cause(err)
+	return cause == ErrMaxRuntimeExceeded || cause == ErrStartTimeoutExceeded
+}
+
+// A processRun contains the state of a single run of a process. It enforces
+// the timeouts of the process and keeps track of whether it is ready.
+type processRun struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	timers  []*runTimer
+	failure error // the reason why the run was aborted by prox (if any)
+	ready   bool
+	paused  bool
+}
+
+// newProcessRun creates a new run of the configured process and immediately
+// starts the timers of its timeouts.
+func newProcessRun(ctx context.Context, conf Process) *processRun {
+	r := new(processRun)
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if d := conf.Timeout; d > 0 {
+		r.timers = append(r.timers, newRunTimer(d, func() {
+			r.abort(errors.Wrapf(ErrMaxRuntimeExceeded, "process was still running after %s", d))
+		}))
+	}
+
+	if d := conf.StartTimeout; d > 0 {
+		r.timers = append(r.timers, newRunTimer(d, func() {
+			r.mu.Lock()
+			ready := r.ready
+			r.mu.Unlock()
+
+			if !ready {
+				r.abort(errors.Wrapf(ErrStartTimeoutExceeded, "process was not ready after %s", d))
+			}
+		}))
+	}
+
+	return r
+}
+
+// abort stops the run because of the given reason. Only the first reason is
+// reported when the run has finished.
+func (r *processRun) abort(reason error) {
+	r.mu.Lock()
+	if r.failure == nil {
+		r.failure = reason
+	}
+	r.mu.Unlock()
+
+	r.cancel()
+}
+
+// markReady marks the run as ready. It returns false if it was ready already.
+func (r *processRun) markReady() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ready {
+		return false
+	}
+
+	r.ready = true
+	return true
+}
+
+// pause suspends the timeouts of the run. It returns false if the run was
+// paused already.
+func (r *processRun) pause() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.paused {
+		return false
+	}
+
+	for _, t := range r.timers {
+		t.stop()
+	}
+
+	r.paused = true
+	return true
+}
+
+// resume continues the timeouts of a paused run with the time that was left
+// when it was paused. It returns false if the run was not paused.
+func (r *processRun) resume() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.paused {
+		return false
+	}
+
+	for _, t := range r.timers {
+		t.start()
+	}
+
+	r.paused = false
+	return true
+}
+
+// isPaused returns true if the run is currently paused.
+func (r *processRun) isPaused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.paused
+}
+
+// finish stops all timers and determines the status of the finished run from
+// the error that was returned by the process.
+func (r *processRun) finish(err error) (ExitStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.timers {
+		t.stop()
+	}
+	r.cancel()
+
+	switch {
+	case r.failure != nil:
+		return StatusError, r.failure
+	case err == context.Canceled:
+		return StatusInterrupted, err
+	case err != nil:
+		return StatusError, err
+	default:
+		return StatusSuccess, nil
+	}
+}
+
+// A runTimer calls a function once a duration has elapsed. Unlike a
+// time.Timer it can be stopped and started again with the remaining duration.
+type runTimer struct {
+	fire      func()
+	remaining time.Duration
+	started   time.Time
+	timer     *time.Timer // nil while the timer is stopped
+	fired     bool
+}
+
+// newRunTimer creates a runTimer and immediately starts it.
+func newRunTimer(d time.Duration, fire func()) *runTimer {
+	t := &runTimer{fire: fire, remaining: d}
+	t.start()
+	return t
+}
+
+// start starts the timer with its remaining duration.
+func (t *runTimer) start() {
+	if t.timer != nil || t.fired {
+		return
+	}
+
+	t.started = time.Now()
+	t.timer = time.AfterFunc(t.remaining, t.fire)
+}
+
+// stop stops the timer and keeps the remaining duration. A timer that has
+// fired already is not started again.
+func (t *runTimer) stop() {
+	if t.timer == nil {
+		return
+	}
+
+	if t.timer.Stop() {
+		t.remaining -= time.Since(t.started)
+	} else {
+		t.fired = true
+	}
+
+	t.timer = nil
+}
+
+// readyWriter is an io.Writer that calls a function once a line of output
+// matches the ready condition of a process. It expects to receive complete
+// lines so it should be wrapped into a bufferedWriter.
+type readyWriter struct {
+	match func(line string) bool
+	ready func()
+}
+
+// newReadyWriter creates a readyWriter for the given condition which is either
+// a regular expression like "/listening on/i" or a plain string that must be
+// contained in the line. If the condition is empty, the first line matches.
+func newReadyWriter(condition string, ready func()) readyWriter {
+	w := readyWriter{ready: ready}
+	switch re := parseValueRegex(condition); {
+	case condition == "":
+		w.match = func(string) bool { return true }
+	case re != nil:
+		w.match = re.MatchString
+	default:
+		w.match = func(line string) bool {
+			return strings.Contains(line, condition)
+		}
+	}
+
+	return w
+}
+
+func (w readyWriter) Write(line []byte) (int, error) {
+	if w.match(strings.TrimRight(string(line), "\r\n")) {
+		w.ready()
+	}
+
+	return len(line), nil
+}
@@ -0,0 +1,127 @@
+package prox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Process timeouts", func() {
+	var (
+		executor *TestExecutor
+		output   *Buffer
+	)
+
+	BeforeEach(func() {
+		output = NewBuffer()
+		executor = TestNewExecutor(output)
+		executor.DisableColoredOutput()
+	})
+
+	run := func(pp ...Process) chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- executor.Executor.Run(context.Background(), pp)
+		}()
+		return done
+	}
+
+	blocking := RunnerFunc(func(ctx context.Context, w io.Writer) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	It("should fail a process that exceeds its maximum runtime", func() {
+		done := run(Process{Name: "task", Runner: blocking, Timeout: 50 * time.Millisecond})
+
+		var err error
+		Eventually(done).Should(Receive(&err))
+		Expect(errors.Cause(err)).To(Equal(ErrMaxRuntimeExceeded))
+		Expect(err.Error()).To(ContainSubstring("process was still running after 50ms"))
+		Expect(output).To(Say(`Process timed out\s+{"process_name":"task"`))
+	})
+
+	It("should fail a process that does not become ready in time", func() {
+		done := run(Process{Name: "service", Runner: blocking, StartTimeout: 50 * time.Millisecond})
+
+		var err error
+		Eventually(done).Should(Receive(&err))
+		Expect(errors.Cause(err)).To(Equal(ErrStartTimeoutExceeded))
+		Expect(err.Error()).To(ContainSubstring("process was not ready after 50ms"))
+	})
+
+	It("should not fail a process that becomes ready in time", func() {
+		events, unsubscribe := executor.events.subscribe()
+		defer unsubscribe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- executor.Executor.Run(ctx, []Process{{
+				Name:         "service",
+				StartTimeout: 100 * time.Millisecond,
+				Ready:        "/LISTENING/i",
+				Runner: RunnerFunc(func(ctx context.Context, w io.Writer) error {
+					fmt.Fprintln(w, "Starting up")
+					fmt.Fprintln(w, "Listening on :8080")
+					<-ctx.Done()
+					return ctx.Err()
+				}),
+			}})
+		}()
+
+		Eventually(events).Should(Receive(haveEventType(EventReady)))
+		Consistently(done, "200ms").ShouldNot(Receive())
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
+
+var _ = Describe("processRun", func() {
+	It("should continue its timeouts with the remaining time after a pause", func() {
+		run := newProcessRun(context.Background(), Process{Timeout: 100 * time.Millisecond})
+		time.Sleep(50 * time.Millisecond)
+
+		Expect(run.pause()).To(BeTrue())
+		Expect(run.pause()).To(BeFalse())
+		Consistently(run.ctx.Done(), "200ms").ShouldNot(BeClosed())
+
+		Expect(run.resume()).To(BeTrue())
+		Expect(run.resume()).To(BeFalse())
+		Eventually(run.ctx.Done(), "90ms").Should(BeClosed())
+
+		_, err := run.finish(context.Canceled)
+		Expect(errors.Cause(err)).To(Equal(ErrMaxRuntimeExceeded))
+	})
+})
+
+var _ = Describe("readyWriter", func() {
+	DescribeTable("ready conditions",
+		func(condition, line string, expected bool) {
+			var ready bool
+			w := newReadyWriter(condition, func() { ready = true })
+			w.Write([]byte(line + "\n"))
+			Expect(ready).To(Equal(expected))
+		},
+
+		Entry("no condition", "", "anything", true),
+		Entry("contained string", "ready", "server is ready now", true),
+		Entry("missing string", "ready", "server is starting", false),
+		Entry("regular expression", "/listening on :\\d+/", "listening on :8080", true),
+		Entry("case insensitive regular expression", "/LISTENING/i", "listening on :8080", true),
+		Entry("not matching regular expression", "/listening on :\\d+/", "listening on :http", false),
+	)
+})
+
+// haveEventType matches events of the given type.
+func haveEventType(t EventType) OmegaMatcher {
+	return WithTransform(func(e Event) EventType { return e.Type }, Equal(t))
+}